@@ -24,9 +24,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/AddSong": {
+        "/api/AddAnnotation": {
             "post": {
-                "description": "Добавляет новую песню в базу данных, предварительно получая дополнительную информацию с внешнего API",
+                "description": "Добавляет комментарий к фрагменту строки текста песни: номер строфы, номер строки в строфе и диапазон символов [start, end)",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,35 +34,49 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Songs"
+                    "Annotations"
                 ],
-                "summary": "Добавить песню",
+                "summary": "Добавить аннотацию к строке текста",
                 "parameters": [
                     {
-                        "description": "Данные о песне",
-                        "name": "song",
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID автора",
+                        "name": "X-User-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Привязка к тексту и текст аннотации",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Song"
+                            "$ref": "#/definitions/endpoints.annotationRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Песня успешно добавлена",
+                        "description": "Аннотация добавлена",
                         "schema": {
-                            "$ref": "#/definitions/models.Song"
+                            "$ref": "#/definitions/models.Annotation"
                         }
                     },
                     "400": {
-                        "description": "Некорректный JSON-запрос",
+                        "description": "Некорректные параметры или привязка вне текста",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Информация о песне не найдена во внешнем API",
+                        "description": "Песня не найдена",
                         "schema": {
                             "type": "string"
                         }
@@ -74,7 +88,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера при обращении к API или сохранении в БД",
+                        "description": "Ошибка сервера при сохранении",
                         "schema": {
                             "type": "string"
                         }
@@ -82,9 +96,75 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/EditSong": {
-            "put": {
-                "description": "Обновляет указанные параметры песни в базе данных",
+        "/api/AddFavorite": {
+            "post": {
+                "description": "Отмечает песню как избранную для пользователя из заголовка X-User-Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Events"
+                ],
+                "summary": "Добавить песню в избранное",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Песня добавлена в избранное",
+                        "schema": {
+                            "$ref": "#/definitions/models.Favorite"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни или отсутствует пользователь",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Песня уже в избранном",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/AddReview": {
+            "post": {
+                "description": "Добавляет оценку (1-5) и необязательный отзыв пользователя из заголовка X-User-Id. Один отзыв на песню от пользователя",
                 "consumes": [
                     "application/json"
                 ],
@@ -92,43 +172,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Songs"
+                    "Reviews"
                 ],
-                "summary": "Редактировать песню",
+                "summary": "Оценить песню",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID песни для редактирования",
+                        "description": "ID песни",
                         "name": "songId",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Список параметров для изменения (через запятую), например: group,song,releaseDate",
-                        "name": "paramsToEdit",
-                        "in": "query",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header",
                         "required": true
                     },
                     {
-                        "description": "JSON с новыми значениями полей",
+                        "description": "Оценка и текст отзыва",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/endpoints.reviewRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Песня успешно обновлена",
+                    "201": {
+                        "description": "Отзыв добавлен",
                         "schema": {
-                            "$ref": "#/definitions/models.Song"
+                            "$ref": "#/definitions/models.Review"
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID или параметры запроса",
+                        "description": "Некорректные параметры или оценка вне диапазона",
                         "schema": {
                             "type": "string"
                         }
@@ -140,13 +220,19 @@ const docTemplate = `{
                         }
                     },
                     "405": {
-                        "description": "Неверный метод запроса (требуется PUT)",
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже оставил отзыв на эту песню",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера при обновлении",
+                        "description": "Ошибка сервера",
                         "schema": {
                             "type": "string"
                         }
@@ -154,9 +240,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/GetSongText": {
-            "get": {
-                "description": "Возвращает текст песни, разбитый на страницы по указанному лимиту строк",
+        "/api/AddSong": {
+            "post": {
+                "description": "Принимает новую песню сразу (статус pending) и ставит задачу на получение дополнительной информации от провайдера метаданных (см. METADATA_PROVIDERS). Статус задачи - /api/GetJob.\nПо умолчанию releaseDate, text и link берутся только из провайдера. В режиме mode=manual поля клиента проверяются и сохраняются, а данные провайдера заполняют пустые поля или заменяют их по политике ENRICHMENT_PRECEDENCE; если провайдер не знает песню, она остается со статусом manual",
                 "consumes": [
                     "application/json"
                 ],
@@ -166,50 +252,66 @@ const docTemplate = `{
                 "tags": [
                     "Songs"
                 ],
-                "summary": "Получить текст песни постранично",
+                "summary": "Добавить песню",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID песни",
-                        "name": "songId",
-                        "in": "query",
-                        "required": true
+                        "description": "Данные о песне",
+                        "name": "song",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.addSongRequest"
+                        }
                     },
                     {
-                        "type": "integer",
-                        "description": "Номер страницы (по умолчанию 1)",
-                        "name": "page",
+                        "type": "string",
+                        "description": "manual - принять поля клиента",
+                        "name": "mode",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Количество строф на странице (по умолчанию 2)",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "Если такая песня уже есть (без учета регистра, пунктуации, The и feat.): error - 409 (по умолчанию), skip - вернуть существующую, update - обновить существующую",
+                        "name": "onDuplicate",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Текст песни постранично",
+                        "description": "Песня уже существует (skip, update), duplicate=true",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "202": {
+                        "description": "Песня принята, jobId - ID задачи обогащения",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Некорректные параметры запроса",
+                        "description": "Некорректный JSON-запрос или некорректные поля",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Песня не найдена или страница вне диапазона",
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "405": {
-                        "description": "Неверный метод запроса (требуется GET)",
+                    "409": {
+                        "description": "Песня уже существует, existingId - ее ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении в БД",
                         "schema": {
                             "type": "string"
                         }
@@ -217,52 +319,84 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/deleteSong": {
-            "delete": {
-                "description": "Удаляет песню из базы данных по её ID",
+        "/api/AddSongFromFile": {
+            "post": {
+                "description": "Читает теги файла MP3 (ID3v1, ID3v2.2-2.4), FLAC или Ogg (Vorbis comment): исполнитель, название, жанр, дата, текст (USLT, LYRICS) и обложку.\nС dryRun=true возвращает предпросмотр: теги, заполненный запрос AddSong (его можно исправить и отправить в AddSong?mode=manual), обложку в base64 и предупреждения. Без dryRun песня сохраняется как AddSong?mode=manual, обложка из тегов (JPEG, PNG, WebP) становится обложкой песни",
+                "consumes": [
+                    "application/octet-stream",
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Songs"
                 ],
-                "summary": "Удалить песню",
+                "summary": "Добавить песню из аудиофайла",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID песни для удаления",
-                        "name": "songId",
-                        "in": "query",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Только предпросмотр, без сохранения",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "error (по умолчанию), skip или update",
+                        "name": "onDuplicate",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Аудиофайл (или поле file формы multipart)",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Песня успешно удалена",
+                        "description": "Предпросмотр (dryRun): tags, request, cover, duplicateOf, warnings",
                         "schema": {
-                            "$ref": "#/definitions/models.Song"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "202": {
+                        "description": "Песня принята, задача обогащения поставлена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID песни или отсутствует параметр songId",
+                        "description": "Неизвестный формат файла, ошибочные теги или нет исполнителя и названия",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Песня не найдена",
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "405": {
-                        "description": "Неверный метод запроса (требуется DELETE)",
+                    "409": {
+                        "description": "Песня уже существует",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше 200 МБ",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера при удалении песни",
+                        "description": "Ошибка сервера при сохранении",
                         "schema": {
                             "type": "string"
                         }
@@ -270,68 +404,3601 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/getSongs": {
-            "get": {
-                "description": "Возвращает список песен с возможностью фильтрации по группе и названию, а также пагинацией",
+        "/api/AddSongLink": {
+            "post": {
+                "description": "Проверяет ссылку по правилам платформы и сохраняет ее канонический адрес: для YouTube - watch?v=ID из youtu.be, shorts и embed, для Spotify - open.spotify.com из spotify: URI, для Apple Music - music.apple.com. Платформа определяется по адресу, переданная платформа должна с ним совпадать (other - любая ссылка http(s))\nЕсли у песни нет прежней ссылки link, ею становится первая ссылка YouTube или первая добавленная",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Songs"
+                    "Links"
                 ],
-                "summary": "Получить список песен",
+                "summary": "Добавить ссылку песни",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Фильтр по названию группы",
-                        "name": "group",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Фильтр по названию песни",
-                        "name": "song",
-                        "in": "query"
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Номер страницы (по умолчанию 1)",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Ссылка и необязательная платформа",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.addLinkRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Список найденных песен",
+                    "201": {
+                        "description": "link - сохраненная models.SongLink, legacyLink - поле link песни",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Song"
-                            }
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос (например, если страница вне диапазона)",
+                        "description": "Некорректная ссылка или платформа",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "Ссылка уже есть у песни",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
-                        "description": "Ошибка сервера при получении данных из БД",
+                        "description": "Ошибка сервера при сохранении",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Song": {
-            "type": "object",
-            "properties": {
-                "createdAt": {
-                    "type": "string"
+        },
+        "/api/AddSongRelation": {
+            "post": {
+                "description": "Создает направленную связь fromSongId -\u003e toSongId: cover-of (кавер), remix-of (ремикс), live-version-of (концертная версия), samples (использует семпл) или same-composition (то же произведение, связь симметрична).\nНаправленные связи не могут образовать цикл: песня не может происходить от самой себя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Relations"
+                ],
+                "summary": "Связать песни",
+                "parameters": [
+                    {
+                        "description": "Песни и тип связи",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.relationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная связь",
+                        "schema": {
+                            "$ref": "#/definitions/models.SongRelation"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос, неизвестный тип или цикл",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Связь уже есть",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteAnnotation": {
+            "delete": {
+                "description": "Удаляет аннотацию. Автор может удалить только свою аннотацию, администратор - любую",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Annotations"
+                ],
+                "summary": "Удалить аннотацию",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID аннотации",
+                        "name": "annotationId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аннотация удалена",
+                        "schema": {
+                            "$ref": "#/definitions/models.Annotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID аннотации",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Аннотация принадлежит другому пользователю",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Аннотация не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteAudio": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audio"
+                ],
+                "summary": "Удалить аудиофайл песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Удаленный аудиофайл",
+                        "schema": {
+                            "$ref": "#/definitions/models.AudioAsset"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "У песни нет аудиофайла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при удалении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteCover": {
+            "delete": {
+                "description": "Удаляет обложку песни (songId) или альбома (group и album)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Covers"
+                ],
+                "summary": "Удалить обложку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Группа альбома",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название альбома",
+                        "name": "album",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deleted: true",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Обложка не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при удалении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteEnrichmentCache": {
+            "delete": {
+                "description": "Удаляет запись кэша обогащения для group и song, либо весь кэш при all=true. Только для администратора",
+                "tags": [
+                    "Enrichment"
+                ],
+                "summary": "Сброс кэша внешнего API",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название группы",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название песни",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Очистить весь кэш",
+                        "name": "all",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Кэш сброшен"
+                    },
+                    "400": {
+                        "description": "Не указаны group и song",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуются права администратора",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Кэш выключен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка удаления кэша",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteFavorite": {
+            "delete": {
+                "description": "Убирает песню из избранного пользователя из заголовка X-User-Id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Events"
+                ],
+                "summary": "Удалить песню из избранного",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Песня удалена из избранного"
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни или отсутствует пользователь",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена в избранном",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteReview": {
+            "delete": {
+                "description": "Удаляет отзыв. Пользователь может удалить только свой отзыв, администратор - любой",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Удалить отзыв",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID отзыва",
+                        "name": "reviewId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отзыв удален",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID отзыва",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Отзыв принадлежит другому пользователю",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Отзыв не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteSongLink": {
+            "delete": {
+                "description": "Удаляет ссылку (linkId). Если это была прежняя ссылка link песни, ее место занимает первая оставшаяся ссылка YouTube или первая оставшаяся ссылка",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "Удалить ссылку песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID ссылки",
+                        "name": "linkId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deleted: true, legacyLink - поле link песни",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID ссылки",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при удалении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteSongRelation": {
+            "delete": {
+                "description": "Удаляет связь (relationId)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Relations"
+                ],
+                "summary": "Удалить связь песен",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID связи",
+                        "name": "relationId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deleted: true",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID связи",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Связь не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при удалении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/DeleteTranslation": {
+            "delete": {
+                "description": "Удаляет перевод текста песни на указанный язык",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Translations"
+                ],
+                "summary": "Удалить перевод",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Язык перевода (BCP-47)",
+                        "name": "lang",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Перевод удален",
+                        "schema": {
+                            "$ref": "#/definitions/models.Translation"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Перевод не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при удалении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/EditReview": {
+            "put": {
+                "description": "Обновляет оценку и текст отзыва. Редактировать можно только свой отзыв",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Редактировать свой отзыв",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID отзыва",
+                        "name": "reviewId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Новые оценка и текст отзыва",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.reviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отзыв обновлен",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры или оценка вне диапазона",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Отзыв принадлежит другому пользователю",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Отзыв не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/EditSong": {
+            "put": {
+                "description": "Обновляет указанные параметры песни в базе данных",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Редактировать песню",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни для редактирования",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Список параметров для изменения (через запятую), например: group,song,releaseDate",
+                        "name": "paramsToEdit",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON с новыми значениями полей",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песня успешно обновлена",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID или параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Песня с такими group и song уже есть, existingId - ее ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при обновлении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/EnrichmentMetrics": {
+            "get": {
+                "description": "Количество запросов к внешнему API обогащения, доля ошибок, задержки и состояние circuit breaker",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Enrichment"
+                ],
+                "summary": "Метрики внешнего API",
+                "responses": {
+                    "200": {
+                        "description": "Метрики",
+                        "schema": {
+                            "$ref": "#/definitions/enrichment.MetricsSnapshot"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ExportPlaylist": {
+            "get": {
+                "description": "Выгружает плейлист (playlistId) или список песен (songIds) в формате M3U8, XSPF или PLS, адрес трека - ссылка песни (link). В M3U8 и PLS песни без ссылки пропускаются, их число - в заголовке X-Skipped-Entries",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Playlists"
+                ],
+                "summary": "Экспорт плейлиста",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "m3u8, xspf или pls",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID плейлиста",
+                        "name": "playlistId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID песен через запятую, если playlistId не указан",
+                        "name": "songIds",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл плейлиста",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ExportSongs": {
+            "get": {
+                "description": "Потоково выгружает все песни, подходящие под фильтры, в CSV, NDJSON или JSON-массив. Строки читаются из БД курсором, поэтому размер выгрузки не ограничен памятью сервера",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Выгрузить библиотеку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (по умолчанию), ndjson или json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поля через запятую, например id,group,song,releaseDate (по умолчанию все)",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Сжать ответ (Content-Encoding: gzip)",
+                        "name": "gzip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию группы",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию песни",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по жанру",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по статусу обогащения",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл выгрузки",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetBrokenLinks": {
+            "get": {
+                "description": "Отчет фоновой проверки ссылок (LINKCHECK_INTERVAL): ссылки, последняя проверка которых не удалась, с кодом ответа или ошибкой и числом неудачных проверок подряд. Сначала ссылки с самой длинной серией неудач",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "Неработающие ссылки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Минимум неудачных проверок подряд (по умолчанию 1)",
+                        "name": "minStreak",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Только ссылки этой платформы",
+                        "name": "platform",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер страницы (по умолчанию 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество ссылок на странице (по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "links - ссылки (models.SongLink) с group и song песни, page, perPage, total",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка чтения из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetDuplicates": {
+            "get": {
+                "description": "Ищет в библиотеке пары песен одного исполнителя, которые вероятно являются одной песней: одинаковый ключ (старые записи), одинаковое название без версии (\"Creep (Acoustic)\", \"Creep - Live\") или похожее название",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Возможные дубликаты",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Минимальная похожесть названий от 0 до 1 (по умолчанию 0.85)",
+                        "name": "threshold",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер страницы (по умолчанию 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество пар на странице (по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пары песен с похожестью и причиной (key, version, title)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный threshold",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка чтения из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetEnrichmentCache": {
+            "get": {
+                "description": "Показывает записи кэша обогащения (в памяти и в БД). С параметрами group и song возвращает одну запись. Только для администратора",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Enrichment"
+                ],
+                "summary": "Кэш ответов внешнего API",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название группы",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название песни",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимум записей (по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Записи кэша",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Требуются права администратора",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Запись не найдена или кэш выключен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка чтения из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetImage": {
+            "get": {
+                "description": "Отдает изображение обложки или его уменьшенную копию (JPEG). Адрес содержит хеш содержимого, поэтому ответ кешируется навсегда",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp"
+                ],
+                "tags": [
+                    "Covers"
+                ],
+                "summary": "Изображение",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "SHA-256 изображения",
+                        "name": "hash",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "64, 300 или 600 - уменьшенная копия, без параметра - оригинал",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Изображение не изменилось",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Изображение не найдено",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET или HEAD)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetJob": {
+            "get": {
+                "description": "Возвращает статус фоновой задачи получения информации о песне: queued, running, done, failed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Статус задачи обогащения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID задачи",
+                        "name": "jobId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Задача",
+                        "schema": {
+                            "$ref": "#/definitions/models.EnrichmentJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID задачи",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Задача не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetLyricsAtTime": {
+            "get": {
+                "description": "Возвращает строку, которая звучит в момент t (мс), а также соседние строки для плеера",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Строка текста в момент времени",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Время от начала песни в мс",
+                        "name": "t",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество следующих строк (по умолчанию 1)",
+                        "name": "context",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текущая и следующие строки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Синхронизированный текст не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetPlaylist": {
+            "get": {
+                "description": "Возвращает плейлист и его песни по порядку",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Playlists"
+                ],
+                "summary": "Получить плейлист",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID плейлиста",
+                        "name": "playlistId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "playlist и songs",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID плейлиста",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Плейлист не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetReviews": {
+            "get": {
+                "description": "Возвращает отзывы песни постранично, новые первыми. Скрытые отзывы видны только администратору",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Получить отзывы песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер страницы (по умолчанию 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество отзывов на странице (по умолчанию 10)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отзывы постранично",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetSongLinks": {
+            "get": {
+                "description": "Возвращает внешние ссылки песни по платформам (youtube, spotify, applemusic, bandcamp, soundcloud, other) с каноническим адресом, ID на платформе и адресом плеера для встраивания, status, httpStatus, failureStreak и checkedAt - результат последней фоновой проверки. Поле link - прежняя одиночная ссылка для старых клиентов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "Получить ссылки песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Только ссылки этой платформы",
+                        "name": "platform",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "songId, link, links - список models.SongLink",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetSongProvenance": {
+            "get": {
+                "description": "Для полей releaseDate, text и link показывает, откуда взято текущее значение: client, edit или имя провайдера метаданных",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Источники данных песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Источники полей",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FieldProvenance"
+                            }
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetSongText": {
+            "get": {
+                "description": "Возвращает текст песни, разбитый на страницы по указанному лимиту строк, вместе с аннотациями к строкам (verse - абсолютный номер строфы)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Получить текст песни постранично",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер страницы (по умолчанию 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество строф на странице (по умолчанию 2)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Язык перевода (BCP-47). Если перевода нет, возвращается оригинал и fallback=true",
+                        "name": "lang",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "side-by-side - оригинал и перевод рядом, выровненные по номеру строфы",
+                        "name": "mode",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текст песни постранично",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена или страница вне диапазона",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetSongVersions": {
+            "get": {
+                "description": "Возвращает всю семью песни: песни, связанные с ней связями любого направления и через другие песни (каверы каверов, ремиксы концертных версий и т.д.), и сами связи. originals - песни семьи, которые не являются кавером, ремиксом или концертной версией другой песни семьи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Relations"
+                ],
+                "summary": "Версии песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Учитывать только эти типы связей через запятую, например cover-of,remix-of",
+                        "name": "types",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "songId, songs, relations (models.SongRelation), originals, truncated - семья больше 1000 песен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка чтения из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetSyncedLyrics": {
+            "get": {
+                "description": "Возвращает синхронизированный текст песни в JSON (начало и конец строк в мс) или исходный LRC",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Получить синхронизированный текст",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (по умолчанию) или lrc",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Синхронизированный текст",
+                        "schema": {
+                            "$ref": "#/definitions/lyrics.LRC"
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Синхронизированный текст не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/GetTranslations": {
+            "get": {
+                "description": "Возвращает все переводы текста песни",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Translations"
+                ],
+                "summary": "Список переводов песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Переводы",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Translation"
+                            }
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ImportLibrary": {
+            "post": {
+                "description": "Импортирует песни и плейлисты из медиатеки iTunes / Apple Music (Library.xml) или Rhythmbox (rhythmdb.xml и необязательный playlists.xml). Исполнитель становится группой, название - песней, год или дата выпуска - releaseDate.\nФайл передается телом запроса или формой multipart (поля library и playlists). Подкасты, видео и радио пропускаются. В ответе - результат каждого трека: created, merged, updated, skipped или failed, и созданные плейлисты",
+                "consumes": [
+                    "text/xml",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Playlists"
+                ],
+                "summary": "Импорт медиатеки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Владелец плейлистов",
+                        "name": "X-User-Id",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "itunes или rhythmbox (по умолчанию по содержимому)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "merge (по умолчанию, заполнить только пустые поля), skip, update или error",
+                        "name": "onDuplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Поставить задачи обогащения новых песен",
+                        "name": "enrich",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Импортировать плейлисты (по умолчанию true)",
+                        "name": "playlists",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Проверить файл без сохранения",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Содержимое Library.xml или rhythmdb.xml",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "source, report - результат треков (importer.Report), playlists - плейлисты медиатеки, error - если плейлисты не сохранены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "207": {
+                        "description": "Импорт прерван ошибкой сервера: report - записанные треки, error - причина, плейлисты не созданы",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры или файл",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше 256 МБ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ImportPlaylist": {
+            "post": {
+                "description": "Читает файл плейлиста (M3U8, XSPF, PLS), ищет песни в библиотеке по исполнителю и названию (или по ссылке) и создает плейлист из найденных. В ответе - записи, для которых песня не найдена",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Playlists"
+                ],
+                "summary": "Импорт плейлиста",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Владелец плейлиста",
+                        "name": "X-User-Id",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "m3u8, xspf или pls",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название плейлиста (по умолчанию из файла)",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Содержимое файла плейлиста",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "playlist, matched - число найденных песен, unmatched - ненайденные записи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный формат или файл",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ImportSongs": {
+            "post": {
+                "description": "Импортирует песни из CSV (первая строка - заголовок) или NDJSON (по одному объекту models.Song в строке). Каждая строка проверяется как в AddSong?mode=manual, песни сохраняются пачками в транзакциях.\nЗаголовки CSV group/artist, song/title, genre, album, releaseDate/year, text/lyrics, link/url распознаются автоматически, другие задаются параметром columns. В ответе - результат каждой строки: created, updated, merged, skipped или failed с причиной",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Массовый импорт песен",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv или ndjson (по умолчанию по Content-Type)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Соответствие заголовков CSV полям, например Artist:group,Title:song,Year:releaseDate (остальные столбцы не используются)",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Поставить задачи обогащения, данные файла сохраняются как в режиме manual",
+                        "name": "enrich",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "skip (по умолчанию), update, merge (заполнить только пустые поля) или error",
+                        "name": "onDuplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Проверить файл без сохранения",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Содержимое файла",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "report - результат строк (importer.Report), ignoredColumns - неиспользованные столбцы CSV, error - если файл прочитан не полностью",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "207": {
+                        "description": "Импорт прерван ошибкой сервера: report - записанные строки, error - причина",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры или формат файла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше 64 МБ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/MergeSongs": {
+            "post": {
+                "description": "Переносит песню duplicateId в песню songId: для каждого поля (group, song, genre, releaseDate, text, link) можно выбрать, чье значение остается (\"song\" или \"duplicate\", по умолчанию song, пустые поля заполняются из дубликата).\nПрослушивания, записи плейлистов, избранное, отзывы, аннотации, синхронизированный текст, переводы, аудиофайл, обложка, ссылки, связи с другими песнями, задачи и источники полей переносятся, дубликат удаляется, а запросы по его ID перенаправляются (301). Все изменения выполняются в одной транзакции. Только для администратора",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Объединить дубликаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID песни, которая остается",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID дубликата, который удаляется",
+                        "name": "duplicateId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Выбор значений полей",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.mergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песни объединены",
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.mergeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуются права администратора",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при объединении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ModerateReview": {
+            "put": {
+                "description": "Администратор скрывает отзыв из выдачи и из рейтинга песни или возвращает его",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Скрыть или показать отзыв (модерация)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID отзыва",
+                        "name": "reviewId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true - скрыть, false - показать",
+                        "name": "hidden",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отзыв обновлен",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуются права администратора",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Отзыв не найден",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ReEnrichSongs": {
+            "post": {
+                "description": "Ставит задачи повторного получения releaseDate, text и link от провайдера метаданных для одной песни (songId) или набора по фильтрам. Обновляются только поля, полученные ранее от провайдера; значения клиента, правок и импорта сохраняются, поля неизвестного происхождения следуют ENRICHMENT_PRECEDENCE. Пустые значения провайдера ничего не стирают, статус manual сохраняется.\ndryRun=true ничего не сохраняет и показывает, что изменится (не более 20 песен)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Повторно получить данные песен",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию группы",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию песни",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по статусу: enriched, failed, manual",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Только песни, обновленные раньше чем указанный срок назад, например 720h",
+                        "name": "olderThan",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Показать изменения без сохранения",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Предпросмотр изменений (dryRun)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "202": {
+                        "description": "Задачи поставлены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Требуются права администратора",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песни не найдены",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/RecordPlay": {
+            "post": {
+                "description": "Записывает событие прослушивания песни, используется для построения чартов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Events"
+                ],
+                "summary": "Отметить прослушивание песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Прослушивание записано",
+                        "schema": {
+                            "$ref": "#/definitions/models.Play"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/StreamAudio": {
+            "get": {
+                "description": "Отдает аудиофайл песни. Поддерживает Range (перемотка в плеерах, ответ 206), If-Range, If-None-Match и If-Modified-Since (ответ 304)",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Audio"
+                ],
+                "summary": "Воспроизведение аудиофайла песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Диапазон байт, например bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аудиофайл",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Часть аудиофайла",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "301": {
+                        "description": "Песня объединена с другой, Location - тот же запрос с новым songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Файл не изменился",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена или у нее нет аудиофайла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET или HEAD)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "416": {
+                        "description": "Диапазон вне файла",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/UploadAudio": {
+            "post": {
+                "description": "Сохраняет аудиофайл песни (MP3, FLAC, Ogg, WAV, M4A) в хранилище файлов, заменяя прежний. Тип определяется по содержимому файла. Одинаковые файлы хранятся один раз",
+                "consumes": [
+                    "application/octet-stream",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audio"
+                ],
+                "summary": "Загрузить аудиофайл песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Аудиофайл (или поле file формы multipart)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "audio - models.AudioAsset, url - адрес потока",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни или запрос",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST или PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше 200 МБ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Файл не является аудио",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/UploadCover": {
+            "post": {
+                "description": "Сохраняет обложку песни (songId) или альбома (group и album) в формате JPEG, PNG или WebP и создает уменьшенные копии 64, 300 и 600 пикселей. Одинаковые изображения хранятся один раз. Обложка альбома показывается у песен альбома без своей обложки\nСсылки на обложку возвращаются в поле cover песен",
+                "consumes": [
+                    "application/octet-stream",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Covers"
+                ],
+                "summary": "Загрузить обложку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Группа альбома",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название альбома",
+                        "name": "album",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Изображение (или поле file формы multipart)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "image - models.Image, cover - ссылки на изображение и уменьшенные копии",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры или поврежденное изображение",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST или PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше 10 МБ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Файл не JPEG, PNG или WebP",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/UploadSyncedLyrics": {
+            "put": {
+                "description": "Загружает LRC или enhanced LRC (с таймингом слов) для песни. Текст проверяется и сохраняется рядом с обычным текстом песни, повторная загрузка заменяет предыдущую",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Lyrics"
+                ],
+                "summary": "Загрузить синхронизированный текст (LRC)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Содержимое LRC файла",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Разобранный LRC",
+                        "schema": {
+                            "$ref": "#/definitions/lyrics.LRC"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни или невалидный LRC",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "Слишком большой файл",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/UploadTranslation": {
+            "put": {
+                "description": "Добавляет или заменяет перевод текста песни на язык lang (BCP-47) с указанием переводчика. Строфы разделяются пустой строкой, как в оригинале",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Translations"
+                ],
+                "summary": "Загрузить перевод текста песни",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Язык перевода (BCP-47), например ru, en, pt-BR",
+                        "name": "lang",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Текст перевода и переводчик",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/endpoints.translationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Перевод сохранен",
+                        "schema": {
+                            "$ref": "#/definitions/models.Translation"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется PUT)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/VoteAnnotation": {
+            "post": {
+                "description": "Голос пользователя за (1) или против (-1) аннотации, 0 - отменить голос. Повторный голос заменяет предыдущий",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Annotations"
+                ],
+                "summary": "Проголосовать за аннотацию",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID аннотации",
+                        "name": "annotationId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1, -1 или 0",
+                        "name": "value",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "X-User-Id",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аннотация с обновленным счетом",
+                        "schema": {
+                            "$ref": "#/definitions/models.Annotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Аннотация не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется POST)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при сохранении",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/charts": {
+            "get": {
+                "description": "Возвращает заранее рассчитанный чарт: топ песен, топ исполнителей или трендовые песни за период",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Charts"
+                ],
+                "summary": "Получить чарт",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Тип чарта: songs, artists, trending (по умолчанию songs)",
+                        "name": "chart",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Период: day, week, month, all (по умолчанию week)",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по жанру",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество позиций (по умолчанию 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Чарт",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестный тип чарта или период",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется GET)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/deleteSong": {
+            "delete": {
+                "description": "Удаляет песню из базы данных по её ID\nВместе с песней удаляются ее аудиофайл, обложка и ссылки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Удалить песню",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID песни для удаления",
+                        "name": "songId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Песня успешно удалена",
+                        "schema": {
+                            "$ref": "#/definitions/models.Song"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID песни или отсутствует параметр songId",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Песня не найдена",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Неверный метод запроса (требуется DELETE)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при удалении песни",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/getSongs": {
+            "get": {
+                "description": "Возвращает список песен с возможностью фильтрации по группе и названию, а также пагинацией",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Songs"
+                ],
+                "summary": "Получить список песен",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию группы",
+                        "name": "group",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию песни",
+                        "name": "song",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по жанру",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по статусу обогащения: pending, enriched, failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер страницы (по умолчанию 1)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Сортировка: rating - по средней оценке",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список найденных песен",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Song"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос (например, если страница вне диапазона)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при получении данных из БД",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "endpoints.addLinkRequest": {
+            "type": "object",
+            "properties": {
+                "platform": {
+                    "description": "optional, checked against the URL",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "endpoints.addSongRequest": {
+            "type": "object",
+            "properties": {
+                "album": {
+                    "type": "string"
+                },
+                "genre": {
+                    "type": "string"
+                },
+                "group": {
+                    "type": "string"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "releaseDate": {
+                    "description": "also YYYY-MM, YYYY, DD.MM.YYYY, \"Sep 1991\"",
+                    "type": "string",
+                    "example": "2006-07-16"
+                },
+                "song": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "endpoints.annotationRequest": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "verse": {
+                    "type": "integer"
+                }
+            }
+        },
+        "endpoints.mergeRequest": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "text": "duplicate"
+                    }
+                }
+            }
+        },
+        "endpoints.mergeResult": {
+            "type": "object",
+            "properties": {
+                "discarded": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "mergedId": {
+                    "type": "integer"
+                },
+                "moved": {
+                    "description": "table - rows moved to the song",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "song": {
+                    "$ref": "#/definitions/models.Song"
+                },
+                "winners": {
+                    "description": "field - ID of the song whose value was kept",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "endpoints.relationRequest": {
+            "type": "object",
+            "properties": {
+                "fromSongId": {
+                    "type": "integer"
+                },
+                "toSongId": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "cover-of"
+                }
+            }
+        },
+        "endpoints.reviewRequest": {
+            "type": "object",
+            "properties": {
+                "rating": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "endpoints.translationRequest": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                },
+                "translator": {
+                    "type": "string"
+                }
+            }
+        },
+        "enrichment.MetricsSnapshot": {
+            "type": "object",
+            "properties": {
+                "avgLatencyMs": {
+                    "type": "number"
+                },
+                "breakerState": {
+                    "type": "string"
+                },
+                "errorRate": {
+                    "type": "number"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "latencyHistogram": {
+                    "description": "\"\u003c=100ms\": count",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "maxLatencyMs": {
+                    "type": "number"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "retries": {
+                    "type": "integer"
+                },
+                "shortCircuited": {
+                    "description": "calls rejected by the open breaker",
+                    "type": "integer"
+                }
+            }
+        },
+        "lyrics.LRC": {
+            "type": "object",
+            "properties": {
+                "enhanced": {
+                    "description": "has word-level timing",
+                    "type": "boolean"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lyrics.SyncedLine"
+                    }
+                },
+                "tags": {
+                    "description": "ar, ti, al, length...",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "lyrics.SyncedLine": {
+            "type": "object",
+            "properties": {
+                "endMs": {
+                    "type": "integer"
+                },
+                "startMs": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "words": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/lyrics.SyncedWord"
+                    }
+                }
+            }
+        },
+        "lyrics.SyncedWord": {
+            "type": "object",
+            "properties": {
+                "endMs": {
+                    "type": "integer"
+                },
+                "startMs": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Annotation": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "line": {
+                    "description": "line index in verse",
+                    "type": "integer"
+                },
+                "orphaned": {
+                    "description": "quote not found after text edit",
+                    "type": "boolean"
+                },
+                "quote": {
+                    "description": "annotated text, used to re-anchor after edits",
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "verse": {
+                    "description": "verse index in song text",
+                    "type": "integer"
+                },
+                "votes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AudioAsset": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "fileName": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "songId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Cover": {
+            "type": "object",
+            "properties": {
+                "fromAlbum": {
+                    "description": "the song has no own cover, this is the album one",
+                    "type": "boolean"
+                },
+                "thumbnails": {
+                    "description": "longest side in pixels - URL",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EnrichmentJob": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "manual": {
+                    "description": "keep client fields, merge by precedence policy",
+                    "type": "boolean"
+                },
+                "nextRunAt": {
+                    "type": "string"
+                },
+                "refresh": {
+                    "description": "re-enrichment of a stored song",
+                    "type": "boolean"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "queued, running, done, failed",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Favorite": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FieldProvenance": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "releaseDate, text, link",
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "source": {
+                    "description": "client, edit or provider name",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Play": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "playedAt": {
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Review": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "hidden": {
+                    "description": "hidden by moderator",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "rating": {
+                    "description": "1..5",
+                    "type": "integer"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Song": {
+            "type": "object",
+            "properties": {
+                "album": {
+                    "type": "string"
+                },
+                "cover": {
+                    "description": "cover image URLs, filled by endpoints",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Cover"
+                        }
+                    ]
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "enrichedAt": {
+                    "description": "last successful fetch from upstream",
+                    "type": "string"
+                },
+                "genre": {
+                    "type": "string"
                 },
                 "group": {
                     "type": "string"
@@ -340,17 +4007,136 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "link": {
+                    "description": "legacy, one of Links, YouTube preferred",
                     "type": "string"
                 },
+                "links": {
+                    "description": "external links by platform, filled by endpoints",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SongLink"
+                    }
+                },
+                "ratingAvg": {
+                    "description": "review aggregates, filled by endpoints",
+                    "type": "number"
+                },
+                "ratingCount": {
+                    "type": "integer"
+                },
                 "releaseDate": {
+                    "type": "string",
+                    "example": "1991-09"
+                },
+                "releaseDatePrecision": {
+                    "description": "year, month, day",
                     "type": "string"
                 },
                 "song": {
                     "type": "string"
                 },
+                "status": {
+                    "description": "pending, enriched, failed, manual",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SongLink": {
+            "type": "object",
+            "properties": {
+                "checkError": {
+                    "type": "string"
+                },
+                "checkedAt": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "embedUrl": {
+                    "type": "string"
+                },
+                "externalId": {
+                    "type": "string"
+                },
+                "failureStreak": {
+                    "description": "failed probes in a row",
+                    "type": "integer"
+                },
+                "httpStatus": {
+                    "description": "of the last probe, 0 if no response",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastOkAt": {
+                    "type": "string"
+                },
+                "platform": {
+                    "description": "youtube, spotify, applemusic, bandcamp, soundcloud, other",
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "unchecked, ok, broken",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SongRelation": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "fromSongId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "toSongId": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Translation": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lang": {
+                    "description": "BCP-47 tag, e.g. ru, pt-BR",
+                    "type": "string"
+                },
+                "songId": {
+                    "type": "integer"
+                },
                 "text": {
                     "type": "string"
                 },
+                "translator": {
+                    "type": "string"
+                },
                 "updatedAt": {
                     "type": "string"
                 }
@@ -366,7 +4152,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "",
 	Schemes:          []string{},
 	Title:            "Music API",
-	Description:      "This is a simple music API to manage songs",
+	Description:      "Test task : This is a simple music API to manage songs\nPOST, PUT, PATCH and DELETE accept an Idempotency-Key header: a retry with the same key and body gets the stored first response (Idempotent-Replayed: true), the same key with another body is rejected with 422",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,82 @@
+// Package artwork checks uploaded cover images and makes their thumbnails
+package artwork
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // decoders register themselves for image.Decode
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ThumbnailSizes are the longest sides of generated thumbnails in pixels
+var ThumbnailSizes = []int{64, 300, 600}
+
+// MaxPixels bounds decoding, a small file may declare a huge image
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupported = errors.New("image is not JPEG, PNG or WebP")
+	ErrInvalid     = errors.New("invalid image")
+)
+
+// Sniff tells the image type by content
+func Sniff(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return "image/webp"
+	}
+	return ""
+}
+
+// Decode reads a JPEG, PNG or WebP image, checking its dimensions before decoding pixels
+func Decode(r io.ReadSeeker) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is too large (max %d pixels)", ErrInvalid, cfg.Width, cfg.Height, MaxPixels)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return img, nil
+}
+
+// Thumbnail scales the image to fit a size x size square keeping the aspect ratio,
+// transparent parts become white; smaller images are not enlarged
+func Thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+// EncodeJPEG writes a thumbnail
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
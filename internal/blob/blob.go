@@ -0,0 +1,79 @@
+// Package blob stores binary assets (audio, images) in a local directory or an S3-compatible bucket
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for missing keys
+var ErrNotFound = errors.New("blob not found")
+
+// Info describes a stored blob
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore keeps blobs by key, keys are slash separated paths like "audio/ab/abcdef"
+type BlobStore interface {
+	// Put stores size bytes of r under key, replacing an existing blob
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get reads length bytes from offset, length -1 reads to the end
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes the blob, a missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+var store BlobStore
+
+// Setup builds the store from .env:
+// BLOB_STORE - local (default) or s3
+// BLOB_DIR - directory of the local store (default ./data/blobs)
+// S3_* - bucket of the s3 store, see S3ConfigFromEnv
+func Setup() {
+	s, err := FromEnv()
+	if err != nil {
+		log.Fatal("Failed to setup blob store: ", err)
+	}
+	store = s
+}
+
+func GetStore() BlobStore {
+	return store
+}
+
+func FromEnv() (BlobStore, error) {
+	switch kind := os.Getenv("BLOB_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "./data/blobs"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(S3ConfigFromEnv())
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q, use local or s3", kind)
+	}
+}
+
+// validKey rejects keys that could leave the store root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
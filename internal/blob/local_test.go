@@ -0,0 +1,100 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key string
+		ok  bool
+	}{
+		{"audio/ab/abcdef", true},
+		{"covers/ab/abcdef.jpg", true},
+		{"a..b/c", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../secret", false},
+		{"audio/../../secret", false},
+		{"audio/..", false},
+		{"audio/./x", false},
+		{"audio//x", false},
+		{"audio/", false},
+		{`audio\..\secret`, false},
+		{`..\secret`, false},
+	}
+	for _, tt := range tests {
+		if err := validKey(tt.key); (err == nil) != tt.ok {
+			t.Errorf("validKey(%q) = %v, want ok %v", tt.key, err, tt.ok)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStore(filepath.Join(root, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	data := []byte("0123456789")
+
+	if err := s.Put(ctx, "audio/ab/abcdef", bytes.NewReader(data), int64(len(data)), "audio/mpeg"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := s.Put(ctx, "audio/ab/short", bytes.NewReader(data), 20, ""); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Put() of fewer bytes than size error = %v, want ErrUnexpectedEOF", err)
+	}
+	if _, err := s.Stat(ctx, "audio/ab/short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("partial Put() left a blob: %v", err)
+	}
+
+	r, err := s.Get(ctx, "audio/ab/abcdef", 2, 3)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "234" {
+		t.Errorf("Get(2, 3) = %q, want 234", got)
+	}
+	if info, err := s.Stat(ctx, "audio/ab/abcdef"); err != nil || info.Size != 10 {
+		t.Errorf("Stat() = %+v, %v, want size 10", info, err)
+	}
+
+	if err := s.Delete(ctx, "audio/ab/abcdef"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := s.Delete(ctx, "audio/ab/abcdef"); err != nil {
+		t.Errorf("Delete() of a missing key error = %v", err)
+	}
+	if _, err := s.Get(ctx, "audio/ab/abcdef", 0, -1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing key error = %v, want ErrNotFound", err)
+	}
+
+	// a file next to the store must stay out of reach
+	if err := os.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../secret", "/secret", `..\secret`, filepath.Join(root, "secret")} {
+		if _, err := s.Get(ctx, key, 0, -1); err == nil || !strings.Contains(err.Error(), "invalid blob key") {
+			t.Errorf("Get(%q) error = %v, want invalid blob key", key, err)
+		}
+		if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), ""); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "secret")); string(content) != "secret" {
+		t.Errorf("file outside the store changed")
+	}
+}
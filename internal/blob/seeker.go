@@ -0,0 +1,64 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ReadSeeker reads a blob of known size with seeking, every seek opens a new ranged read.
+// It lets http.ServeContent answer Range requests of any store
+type ReadSeeker struct {
+	ctx    context.Context
+	store  BlobStore
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func NewReadSeeker(ctx context.Context, store BlobStore, key string, size int64) *ReadSeeker {
+	return &ReadSeeker{ctx: ctx, store: store, key: key, size: size}
+}
+
+func (r *ReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.store.Get(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("blob: negative position")
+	}
+	if offset != r.offset {
+		r.Close()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+func (r *ReadSeeker) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
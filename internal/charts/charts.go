@@ -0,0 +1,137 @@
+package charts
+
+import (
+	"log"
+	"math"
+	"music_library_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// chart kinds
+const (
+	TopSongs   = "songs"
+	TopArtists = "artists"
+	Trending   = "trending"
+)
+
+const (
+	maxEntries      = 100            // positions stored per chart
+	favoriteWeight  = 3.0            // one favorite counts as three plays
+	trendHalfLife   = 48 * time.Hour // trending score halves every two days
+	defaultInterval = 15 * time.Minute
+)
+
+// Periods maps period name to its window, zero means all-time
+var Periods = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"all":   0,
+}
+
+// Kinds lists all supported charts
+var Kinds = []string{TopSongs, TopArtists, Trending}
+
+// plays and favorites as one weighted event stream
+const eventsQuery = `(SELECT song_id, played_at AS at, 1.0 AS weight FROM plays
+	UNION ALL
+	SELECT song_id, created_at AS at, ? AS weight FROM favorites) AS e`
+
+// StartWorker recomputes all charts right away and then on every tick
+func StartWorker(db *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	log.Printf("Info: Charts worker started, interval %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := Materialize(db); err != nil {
+				log.Printf("Error: Failed to materialize charts: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Materialize rebuilds every chart for every period and genre
+func Materialize(db *gorm.DB) error {
+	now := time.Now()
+
+	var genres []string
+	if err := db.Model(&models.Song{}).Where("genre <> ''").Distinct().Pluck("genre", &genres).Error; err != nil {
+		return err
+	}
+	genres = append(genres, "") // "" - all genres
+
+	var entries []models.ChartEntry
+	for _, kind := range Kinds {
+		for period, window := range Periods {
+			for _, genre := range genres {
+				list, err := compute(db, kind, window, genre, now)
+				if err != nil {
+					return err
+				}
+				for i := range list {
+					list[i].Chart = kind
+					list[i].Period = period
+					list[i].Genre = genre
+					list[i].Rank = i + 1
+					list[i].ComputedAt = now
+				}
+				entries = append(entries, list...)
+			}
+		}
+	}
+
+	// swap old charts for new ones in one step
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ChartEntry{}).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(entries, 500).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Info: Charts materialized, %d entries in %s", len(entries), time.Since(now))
+	return nil
+}
+
+func compute(db *gorm.DB, kind string, window time.Duration, genre string, now time.Time) ([]models.ChartEntry, error) {
+	score := "SUM(e.weight)"
+	args := []interface{}{}
+	if kind == Trending {
+		// exponential time decay: weight * 0.5^(age / halfLife)
+		score = "SUM(e.weight * EXP(-? * EXTRACT(EPOCH FROM (?::timestamptz - e.at))))"
+		args = append(args, math.Ln2/trendHalfLife.Seconds(), now)
+	}
+
+	q := db.Table(eventsQuery, favoriteWeight).
+		Joins("JOIN songs s ON s.id = e.song_id")
+	if kind == TopArtists {
+		q = q.Select("s.group_name AS group_name, "+score+" AS score", args...).
+			Group("s.group_name")
+	} else {
+		q = q.Select("e.song_id AS song_id, s.song AS song, s.group_name AS group_name, "+score+" AS score", args...).
+			Group("e.song_id, s.song, s.group_name")
+	}
+	if window > 0 {
+		q = q.Where("e.at >= ?", now.Add(-window))
+	}
+	if genre != "" {
+		q = q.Where("s.genre = ?", genre)
+	}
+
+	var list []models.ChartEntry
+	err := q.Order("score DESC").Limit(maxEntries).Scan(&list).Error
+	return list, err
+}
@@ -1,8 +1,11 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/links"
 	"music_library_api/internal/models"
 	testData "music_library_api/test"
 	"os"
@@ -60,15 +63,106 @@ func GetDB() *gorm.DB {
 	return db
 }
 
+// IsUniqueViolation reports whether err is a unique constraint violation (SQLSTATE 23505)
+func IsUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
 func Migrate() {
-	if !db.Migrator().HasTable(&models.Song{}) {
-		err := db.AutoMigrate(&models.Song{})
+	// AutoMigrate creates missing tables and adds new columns to existing ones
+	err := db.AutoMigrate(
+		&models.Song{},
+		&models.Play{},
+		&models.Favorite{},
+		&models.ChartEntry{},
+		&models.Review{},
+		&models.Annotation{},
+		&models.AnnotationVote{},
+		&models.SyncedLyrics{},
+		&models.Translation{},
+		&models.EnrichmentJob{},
+		&models.FieldProvenance{},
+		&models.EnrichmentCacheEntry{},
+		&models.SongRedirect{},
+		&models.IdempotencyRecord{},
+		&models.Playlist{},
+		&models.PlaylistEntry{},
+		&models.AudioAsset{},
+		&models.Image{},
+		&models.SongCover{},
+		&models.AlbumCover{},
+		&models.SongLink{},
+		&models.SongRelation{},
+	)
+	if err != nil {
+		log.Fatal(" Migration failed:", err)
+	}
+	backfillDedupKeys()
+	backfillSongLinks()
+	log.Println("Database migrated successfully!")
+}
+
+// backfillDedupKeys sets uniqueness keys of songs stored before duplicate detection,
+// a song whose key is taken stays without it and shows up in the duplicates report
+func backfillDedupKeys() {
+	var songs []models.Song
+	if err := db.Where("dedup_key IS NULL").Order("id").Find(&songs).Error; err != nil {
+		log.Fatal("Failed to load songs without dedup key:", err)
+	}
+	if len(songs) == 0 {
+		return
+	}
+
+	var taken []string
+	if err := db.Model(&models.Song{}).Where("dedup_key IS NOT NULL").Pluck("dedup_key", &taken).Error; err != nil {
+		log.Fatal("Failed to load dedup keys:", err)
+	}
+	used := make(map[string]bool, len(taken))
+	for _, key := range taken {
+		used[key] = true
+	}
+
+	for _, song := range songs {
+		key := dedup.Key(song.Group, song.Song)
+		if used[key] {
+			log.Printf("Info: Song %d (%s - %s) is a duplicate, left without dedup key", song.ID, song.Group, song.Song)
+			continue
+		}
+		used[key] = true
+		if err := db.Model(&models.Song{}).Where("id = ?", song.ID).UpdateColumn("dedup_key", key).Error; err != nil {
+			log.Fatal("Failed to set dedup key:", err)
+		}
+	}
+}
+
+// backfillSongLinks moves links of songs stored before typed links into the links collection,
+// the legacy link is canonicalized; a link that does not validate stays legacy only
+func backfillSongLinks() {
+	var songs []models.Song
+	err := db.Select("id", "link").
+		Where("link <> '' AND NOT EXISTS (SELECT 1 FROM song_links WHERE song_links.song_id = songs.id)").
+		Order("id").Find(&songs).Error
+	if err != nil {
+		log.Fatal("Failed to load songs without links:", err)
+	}
+
+	for _, song := range songs {
+		link, err := links.Parse(song.Link, "")
+		if err != nil {
+			log.Printf("Info: Link of song %d is kept as legacy only: %v", song.ID, err)
+			continue
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Song{}).Where("id = ?", song.ID).UpdateColumn("link", link.URL).Error; err != nil {
+				return err
+			}
+			songLink := models.NewSongLink(song.ID, link)
+			return tx.Create(&songLink).Error
+		})
 		if err != nil {
-			log.Fatal(" Migration failed:", err)
+			log.Fatal("Failed to save song link:", err)
 		}
-		log.Println("Database migrated successfully!")
-	} else {
-		log.Println("Info: Table already exists, skipping migration.")
 	}
 }
 
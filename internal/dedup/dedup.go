@@ -0,0 +1,150 @@
+package dedup
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// (feat. X), [ft X], (with X)
+	featBrackets = regexp.MustCompile(`\s*[(\[](feat\.?|ft\.?|featuring|with)\s[^)\]]*[)\]]`)
+	// trailing "feat. X" without brackets
+	featTail = regexp.MustCompile(`\s+(feat\.?|ft\.?|featuring)\s.*$`)
+	// any bracketed part and " - Live", " - Remastered 2011" suffixes, only for near-duplicates
+	extras = regexp.MustCompile(`\s*[(\[][^)\]]*[)\]]|\s+-\s+.*$`)
+)
+
+// Key is the uniqueness key of a song: case, accents, punctuation,
+// the leading "The" and featuring credits are ignored
+func Key(group, song string) string {
+	return Name(group) + "|" + Name(song)
+}
+
+// Name normalizes one artist or title
+func Name(value string) string {
+	value = strings.ToLower(fold(value))
+	value = featBrackets.ReplaceAllString(value, "")
+	value = featTail.ReplaceAllString(value, "")
+	value = strings.ReplaceAll(value, "&", " and ")
+	value = simplify(value)
+	return strings.TrimPrefix(value, "the ")
+}
+
+// fold removes accents: Beyoncé - beyonce
+func fold(value string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, value)
+	if err != nil {
+		return value
+	}
+	return folded
+}
+
+// simplify keeps letters and digits separated by single spaces
+func simplify(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Entry is a song checked for near-duplicates
+type Entry struct {
+	ID    int
+	Group string
+	Song  string
+}
+
+// Pair is two songs that are likely the same
+type Pair struct {
+	A          int     `json:"a"`
+	B          int     `json:"b"`
+	Similarity float64 `json:"similarity"` // 0..1 of titles
+	Reason     string  `json:"reason"`     // key, version or title
+}
+
+// NearDuplicates finds pairs of songs by the same artist whose titles are equal
+// without versions ("Creep (Acoustic)", "Creep - Live") or similar at least by threshold
+func NearDuplicates(entries []Entry, threshold float64) []Pair {
+	byArtist := map[string][]Entry{}
+	for _, e := range entries {
+		artist := Name(e.Group)
+		byArtist[artist] = append(byArtist[artist], e)
+	}
+
+	var pairs []Pair
+	for _, songs := range byArtist {
+		titles := make([]string, len(songs))
+		bases := make([]string, len(songs))
+		for i, s := range songs {
+			titles[i] = Name(s.Song)
+			bases[i] = Name(extras.ReplaceAllString(strings.ToLower(fold(s.Song)), ""))
+		}
+		for i := 0; i < len(songs); i++ {
+			for j := i + 1; j < len(songs); j++ {
+				a, b := songs[i].ID, songs[j].ID
+				if a > b {
+					a, b = b, a
+				}
+				sim := Similarity(titles[i], titles[j])
+				switch {
+				case titles[i] == titles[j]:
+					pairs = append(pairs, Pair{A: a, B: b, Similarity: 1, Reason: "key"})
+				case bases[i] != "" && bases[i] == bases[j]:
+					pairs = append(pairs, Pair{A: a, B: b, Similarity: sim, Reason: "version"})
+				case sim >= threshold:
+					pairs = append(pairs, Pair{A: a, B: b, Similarity: sim, Reason: "title"})
+				}
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+	return pairs
+}
+
+// Similarity is 1 - edit distance / length of the longer string
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
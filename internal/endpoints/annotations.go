@@ -0,0 +1,255 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/lyrics"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// annotationRequest body of AddAnnotation
+type annotationRequest struct {
+	Verse int    `json:"verse"`
+	Line  int    `json:"line"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Body  string `json:"body"`
+}
+
+// @Summary Добавить аннотацию к строке текста
+// @Description Добавляет комментарий к фрагменту строки текста песни: номер строфы, номер строки в строфе и диапазон символов [start, end)
+// @Tags Annotations
+// @Accept json
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param X-User-Id header string true "ID автора"
+// @Param body body annotationRequest true "Привязка к тексту и текст аннотации"
+// @Success 201 {object} models.Annotation "Аннотация добавлена"
+// @Failure 400 {string} string "Некорректные параметры или привязка вне текста"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/AddAnnotation [post]
+func AddAnnotation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <AddAnnotation> endpoint...")
+
+	userId := r.Header.Get(userIDHeader)
+	if userId == "" {
+		log.Println("Error: Missing X-User-Id header")
+		http.Error(w, "Missing X-User-Id header", http.StatusBadRequest)
+		return
+	}
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	var req annotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		log.Println("Error: Empty annotation body")
+		http.Error(w, "Annotation body is required", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	quote, ok := lyrics.Quote(song.Text, req.Verse, req.Line, req.Start, req.End)
+	if !ok {
+		log.Printf("Error: Anchor %+v is out of song %d text", req, song.ID)
+		http.Error(w, "Anchor is out of song text", http.StatusBadRequest)
+		return
+	}
+
+	annotation := models.Annotation{
+		SongID: song.ID,
+		UserID: userId,
+		Verse:  req.Verse,
+		Line:   req.Line,
+		Start:  req.Start,
+		End:    req.End,
+		Quote:  quote,
+		Body:   req.Body,
+	}
+	if err := db.Create(&annotation).Error; err != nil {
+		log.Printf("Error: Failed to save annotation: %v", err)
+		http.Error(w, "Failed to save annotation", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Annotation %d added to song %d", annotation.ID, song.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(annotation)
+}
+
+// @Summary Проголосовать за аннотацию
+// @Description Голос пользователя за (1) или против (-1) аннотации, 0 - отменить голос. Повторный голос заменяет предыдущий
+// @Tags Annotations
+// @Produce json
+// @Param annotationId query int true "ID аннотации"
+// @Param value query int true "1, -1 или 0"
+// @Param X-User-Id header string true "ID пользователя"
+// @Success 200 {object} models.Annotation "Аннотация с обновленным счетом"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 404 {string} string "Аннотация не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/VoteAnnotation [post]
+func VoteAnnotation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <VoteAnnotation> endpoint...")
+
+	userId := r.Header.Get(userIDHeader)
+	if userId == "" {
+		log.Println("Error: Missing X-User-Id header")
+		http.Error(w, "Missing X-User-Id header", http.StatusBadRequest)
+		return
+	}
+
+	annotationIntId, err := strconv.Atoi(r.URL.Query().Get("annotationId"))
+	if err != nil {
+		log.Printf("Error: Invalid annotationId parameter: %v", err)
+		http.Error(w, "Invalid annotationId parameter", http.StatusBadRequest)
+		return
+	}
+	value, err := strconv.Atoi(r.URL.Query().Get("value"))
+	if err != nil || value < -1 || value > 1 {
+		log.Printf("Error: Invalid value parameter: %q", r.URL.Query().Get("value"))
+		http.Error(w, "Invalid value parameter, use 1, -1 or 0", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var annotation models.Annotation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// the row lock serializes votes on one annotation, otherwise two votes of one user
+		// both pass the delete and hit the unique index, and recounts miss each other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&annotation, annotationIntId).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("annotation_id = ? AND user_id = ?", annotation.ID, userId).
+			Delete(&models.AnnotationVote{}).Error; err != nil {
+			return err
+		}
+		if value != 0 {
+			vote := models.AnnotationVote{AnnotationID: annotation.ID, UserID: userId, Value: value}
+			if err := tx.Create(&vote).Error; err != nil {
+				return err
+			}
+		}
+
+		// recount instead of increment, so the score never drifts
+		var votes int64
+		if err := tx.Model(&models.AnnotationVote{}).Where("annotation_id = ?", annotation.ID).
+			Select("COALESCE(SUM(value), 0)").Scan(&votes).Error; err != nil {
+			return err
+		}
+		annotation.Votes = int(votes)
+		return tx.Model(&annotation).Update("votes", annotation.Votes).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error: Annotation with id %d not found", annotationIntId)
+		http.Error(w, "Annotation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error: Failed to vote for annotation %d: %v", annotationIntId, err)
+		http.Error(w, "Failed to save vote", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(annotation)
+}
+
+// @Summary Удалить аннотацию
+// @Description Удаляет аннотацию. Автор может удалить только свою аннотацию, администратор - любую
+// @Tags Annotations
+// @Produce json
+// @Param annotationId query int true "ID аннотации"
+// @Param X-User-Id header string false "ID пользователя"
+// @Param X-Admin-Token header string false "Токен администратора"
+// @Success 200 {object} models.Annotation "Аннотация удалена"
+// @Failure 400 {string} string "Некорректный ID аннотации"
+// @Failure 403 {string} string "Аннотация принадлежит другому пользователю"
+// @Failure 404 {string} string "Аннотация не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Router /api/DeleteAnnotation [delete]
+func DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteAnnotation> endpoint...")
+
+	annotationIntId, err := strconv.Atoi(r.URL.Query().Get("annotationId"))
+	if err != nil {
+		log.Printf("Error: Invalid annotationId parameter: %v", err)
+		http.Error(w, "Invalid annotationId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var annotation models.Annotation
+	if err := db.First(&annotation, annotationIntId).Error; err != nil {
+		log.Printf("Error: Annotation with id %d not found", annotationIntId)
+		http.Error(w, "Annotation not found", http.StatusNotFound)
+		return
+	}
+
+	userId := r.Header.Get(userIDHeader)
+	if !isAdmin(r) && (userId == "" || annotation.UserID != userId) {
+		log.Printf("Error: User %q tried to delete annotation %d of %s", userId, annotation.ID, annotation.UserID)
+		http.Error(w, "You can delete only your own annotation", http.StatusForbidden)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("annotation_id = ?", annotation.ID).Delete(&models.AnnotationVote{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&annotation).Error
+	})
+	if err != nil {
+		log.Printf("Error: Failed to delete annotation %d: %v", annotation.ID, err)
+		http.Error(w, "Failed to delete annotation", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Annotation %d deleted successfully", annotation.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(annotation)
+}
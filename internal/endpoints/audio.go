@@ -0,0 +1,251 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"music_library_api/internal/blob"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sniffAudio tells the audio type by content, the type sent by the client is not trusted
+func sniffAudio(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")), len(head) > 1 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return "audio/mpeg"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return "audio/ogg"
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return "audio/wav"
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return "audio/mp4"
+	}
+	return ""
+}
+
+func audioURL(songId int) string {
+	return fmt.Sprintf("/api/StreamAudio?songId=%d", songId)
+}
+
+// @Summary Загрузить аудиофайл песни
+// @Description Сохраняет аудиофайл песни (MP3, FLAC, Ogg, WAV, M4A) в хранилище файлов, заменяя прежний. Тип определяется по содержимому файла. Одинаковые файлы хранятся один раз
+// @Tags Audio
+// @Accept octet-stream
+// @Accept mpfd
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param body body string true "Аудиофайл (или поле file формы multipart)"
+// @Success 201 {object} map[string]interface{} "audio - models.AudioAsset, url - адрес потока"
+// @Failure 400 {string} string "Некорректный ID песни или запрос"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST или PUT)"
+// @Failure 413 {string} string "Файл больше 200 МБ"
+// @Failure 415 {string} string "Файл не является аудио"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/UploadAudio [post]
+func UploadAudio(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		log.Printf("Error: Invalid request method. Expected POST or PUT, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <UploadAudio> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	u, err := receiveUpload(w, r, maxAudioSize)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Error: Audio file is larger than %d bytes", maxAudioSize)
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error: Failed to receive audio file: %v", err)
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer u.Close()
+
+	contentType := sniffAudio(u.head)
+	if contentType == "" {
+		log.Printf("Error: Uploaded file is not audio (%s)", http.DetectContentType(u.head))
+		http.Error(w, "File is not MP3, FLAC, Ogg, WAV or M4A audio", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	asset := models.AudioAsset{
+		SongID:      song.ID,
+		Key:         contentKey("audio", u.sha256),
+		ContentType: contentType,
+		Size:        u.size,
+		SHA256:      u.sha256,
+		FileName:    u.name,
+	}
+	if err := u.store(r.Context(), asset.Key, contentType); err != nil {
+		log.Printf("Error: Failed to store audio file: %v", err)
+		http.Error(w, "Error storing file", http.StatusInternalServerError)
+		return
+	}
+
+	var previous models.AudioAsset
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// a release of the same content may have deleted the blob since it was stored
+		if err := lockBlob(tx, asset.Key); err != nil {
+			return err
+		}
+		if err := u.store(r.Context(), asset.Key, contentType); err != nil {
+			return err
+		}
+		if err := tx.Where("song_id = ?", song.ID).Find(&previous).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "song_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"key", "content_type", "size", "sha256", "file_name", "created_at"}),
+		}).Create(&asset).Error
+	})
+	if err != nil {
+		log.Printf("Error: Failed to save audio of song %d: %v", song.ID, err)
+		http.Error(w, "Error saving to database: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if previous.Key != "" && previous.Key != asset.Key {
+		if err := releaseBlob(r.Context(), db, &models.AudioAsset{}, previous.Key); err != nil {
+			log.Printf("Error: Failed to delete replaced audio %s: %v", previous.Key, err)
+		}
+	}
+	log.Printf("Info: Audio of song %d stored: %s, %d bytes", song.ID, contentType, u.size)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", audioURL(song.ID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"audio": asset,
+		"url":   audioURL(song.ID),
+	})
+}
+
+// @Summary Воспроизведение аудиофайла песни
+// @Description Отдает аудиофайл песни. Поддерживает Range (перемотка в плеерах, ответ 206), If-Range, If-None-Match и If-Modified-Since (ответ 304)
+// @Tags Audio
+// @Produce octet-stream
+// @Param songId query int true "ID песни"
+// @Param Range header string false "Диапазон байт, например bytes=0-1023"
+// @Success 200 {file} file "Аудиофайл"
+// @Success 206 {file} file "Часть аудиофайла"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Success 304 {string} string "Файл не изменился"
+// @Failure 400 {string} string "Некорректный ID песни"
+// @Failure 404 {string} string "Песня не найдена или у нее нет аудиофайла"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET или HEAD)"
+// @Failure 416 {string} string "Диапазон вне файла"
+// @Router /api/StreamAudio [get]
+func StreamAudio(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		log.Printf("Error: Invalid request method. Expected GET or HEAD, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <StreamAudio> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	db := database.GetDB()
+	if redirectMerged(w, r, db, songIntId) {
+		return
+	}
+	var song models.Song
+	if err := db.Select("id").First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+	var asset models.AudioAsset
+	if err := db.Where("song_id = ?", songIntId).First(&asset).Error; err != nil {
+		log.Printf("Error: Song %d has no audio", songIntId)
+		http.Error(w, "Audio not found", http.StatusNotFound)
+		return
+	}
+
+	content := blob.NewReadSeeker(r.Context(), blob.GetStore(), asset.Key, asset.Size)
+	defer content.Close()
+
+	// ServeContent answers Range and conditional requests with these headers
+	w.Header().Set("Content-Type", asset.ContentType)
+	w.Header().Set("ETag", `"`+asset.SHA256+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=0, must-revalidate")
+	http.ServeContent(w, r, "", asset.CreatedAt, content)
+}
+
+// @Summary Удалить аудиофайл песни
+// @Tags Audio
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Success 200 {object} models.AudioAsset "Удаленный аудиофайл"
+// @Failure 400 {string} string "Некорректный ID песни"
+// @Failure 404 {string} string "У песни нет аудиофайла"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Failure 500 {string} string "Ошибка сервера при удалении"
+// @Router /api/DeleteAudio [delete]
+func DeleteAudio(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteAudio> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	db := database.GetDB()
+	var asset models.AudioAsset
+	if err := db.Where("song_id = ?", songIntId).First(&asset).Error; err != nil {
+		log.Printf("Error: Song %d has no audio", songIntId)
+		http.Error(w, "Audio not found", http.StatusNotFound)
+		return
+	}
+	if err := db.Delete(&asset).Error; err != nil {
+		log.Printf("Error: Failed to delete audio of song %d: %v", songIntId, err)
+		http.Error(w, "Failed to delete audio", http.StatusInternalServerError)
+		return
+	}
+	// the row is gone, a failed blob delete only leaves an unused file
+	if err := releaseBlob(r.Context(), db, &models.AudioAsset{}, asset.Key); err != nil {
+		log.Printf("Error: Failed to delete audio file %s: %v", asset.Key, err)
+	}
+	log.Printf("Info: Audio of song %d deleted", songIntId)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(asset)
+}
@@ -0,0 +1,248 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"music_library_api/internal/artwork"
+	"music_library_api/internal/database"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
+	"music_library_api/internal/tags"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const maxAudioSize = 200 << 20 // 200 MB, a long FLAC
+
+// coverPreview is the embedded cover in the preview
+type coverPreview struct {
+	MIMEType string `json:"mimeType"`
+	Size     int    `json:"size"`
+	Data     string `json:"data"` // base64
+}
+
+// openAudioFile returns the file of a multipart form (field file) or the raw body
+func openAudioFile(r *http.Request) (io.ReadSeeker, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("no file in the form: %w", err)
+		}
+		return file, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// tagsRequest maps tags to AddSong fields, values that AddSong would reject are dropped with a warning
+func tagsRequest(t *tags.Tags) (addSongRequest, []string) {
+	req := addSongRequest{Group: t.Artist, Song: t.Title, Genre: t.Genre, Album: t.Album, Text: t.Lyrics}
+	if req.Group == "" {
+		req.Group = t.AlbumArtist
+	}
+	var warnings []string
+	if req.Group == "" {
+		warnings = append(warnings, "no artist tag, group is empty")
+	}
+	if req.Song == "" {
+		warnings = append(warnings, "no title tag, song is empty")
+	}
+	if t.Date != "" {
+		if date, precision, err := releasedate.Parse(t.Date); err == nil {
+			req.ReleaseDate = releasedate.Format(date, precision)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("date tag %q is not a date", t.Date))
+		}
+	}
+	if len([]rune(req.Text)) > maxTextLength {
+		req.Text = ""
+		warnings = append(warnings, fmt.Sprintf("lyrics are longer than %d characters", maxTextLength))
+	}
+	return req, warnings
+}
+
+// saveTagCover makes an embedded picture the cover of the song
+func saveTagCover(ctx context.Context, db *gorm.DB, songId int, data []byte) error {
+	contentType := artwork.Sniff(data)
+	if contentType == "" {
+		return artwork.ErrUnsupported
+	}
+	sum := sha256.Sum256(data)
+	_, err := linkImage(ctx, db, bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]), contentType,
+		func(tx *gorm.DB, img *models.Image) error {
+			return tx.Create(&models.SongCover{SongID: songId, ImageID: img.ID}).Error
+		})
+	return err
+}
+
+// @Summary Добавить песню из аудиофайла
+// @Description Читает теги файла MP3 (ID3v1, ID3v2.2-2.4), FLAC или Ogg (Vorbis comment): исполнитель, название, жанр, дата, текст (USLT, LYRICS) и обложку.
+// @Description С dryRun=true возвращает предпросмотр: теги, заполненный запрос AddSong (его можно исправить и отправить в AddSong?mode=manual), обложку в base64 и предупреждения. Без dryRun песня сохраняется как AddSong?mode=manual, обложка из тегов (JPEG, PNG, WebP) становится обложкой песни
+// @Tags Songs
+// @Accept octet-stream
+// @Accept mpfd
+// @Produce json
+// @Param dryRun query bool false "Только предпросмотр, без сохранения"
+// @Param onDuplicate query string false "error (по умолчанию), skip или update"
+// @Param body body string false "Аудиофайл (или поле file формы multipart)"
+// @Success 200 {object} map[string]interface{} "Предпросмотр (dryRun): tags, request, cover, duplicateOf, warnings"
+// @Success 202 {object} map[string]interface{} "Песня принята, задача обогащения поставлена"
+// @Failure 400 {string} string "Неизвестный формат файла, ошибочные теги или нет исполнителя и названия"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 409 {object} map[string]interface{} "Песня уже существует"
+// @Failure 413 {string} string "Файл больше 200 МБ"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/AddSongFromFile [post]
+func AddSongFromFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <AddSongFromFile> endpoint...")
+
+	q := r.URL.Query()
+	dryRun := false
+	if v := q.Get("dryRun"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			log.Printf("Error: Invalid dryRun parameter: %v", err)
+			http.Error(w, "Invalid dryRun parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	onDuplicate := q.Get("onDuplicate")
+	switch onDuplicate {
+	case "":
+		onDuplicate = ingest.OnDuplicateError
+	case ingest.OnDuplicateError, ingest.OnDuplicateSkip, ingest.OnDuplicateUpdate:
+	default:
+		log.Printf("Error: Unknown onDuplicate %q", onDuplicate)
+		http.Error(w, "Unknown onDuplicate, use skip, update or error", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAudioSize)
+	file, err := openAudioFile(r)
+	var t *tags.Tags
+	if err == nil {
+		t, err = tags.Read(file)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Error: Audio file is larger than %d bytes", maxAudioSize)
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error: Failed to read audio tags: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Info: Read %s tags (%s): %q - %q", t.Format, t.Container, t.Artist, t.Title)
+
+	req, warnings := tagsRequest(t)
+	db := database.GetDB()
+
+	if dryRun {
+		response := map[string]interface{}{
+			"tags":     t,
+			"request":  req,
+			"warnings": warnings,
+		}
+		if cover := t.Cover(); cover != nil {
+			response["cover"] = coverPreview{
+				MIMEType: cover.MIMEType,
+				Size:     len(cover.Data),
+				Data:     base64.StdEncoding.EncodeToString(cover.Data),
+			}
+		}
+		if req.Group != "" && req.Song != "" {
+			existing, err := ingest.FindDuplicate(db, req.Group, req.Song)
+			if err != nil {
+				log.Printf("Error: Failed to check duplicates: %v", err)
+				http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				response["duplicateOf"] = existing.ID
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	newSong, err := req.toSong(true)
+	if err != nil {
+		log.Printf("Error: Invalid song from tags: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	existing, err := ingest.FindDuplicate(db, newSong.Group, newSong.Song)
+	if err != nil {
+		log.Printf("Error: Failed to check duplicates: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		handleDuplicate(w, db, existing, newSong, true, onDuplicate)
+		return
+	}
+
+	job, err := ingest.Enqueue(db, &newSong, true)
+	if err != nil {
+		if existing, _ := ingest.FindDuplicate(db, newSong.Group, newSong.Song); existing != nil {
+			handleDuplicate(w, db, existing, newSong, true, onDuplicate)
+			return
+		}
+		log.Printf("Error: Failed to save song to database: %v", err)
+		http.Error(w, "Error saving song to database: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Song %d added from %s tags, enrichment job %d queued", newSong.ID, t.Format, job.ID)
+
+	// the song is stored already, a broken embedded cover is only reported
+	if cover := t.Cover(); cover != nil {
+		if err := saveTagCover(r.Context(), db, newSong.ID, cover.Data); err != nil {
+			log.Printf("Error: Failed to save embedded cover of song %d: %v", newSong.ID, err)
+			warnings = append(warnings, "embedded cover was not saved: "+err.Error())
+		}
+	}
+	songs := []models.Song{newSong}
+	if err := attachCovers(db, songs); err != nil {
+		log.Printf("Error: Failed to get cover of song %d: %v", newSong.ID, err)
+	}
+	if err := attachLinks(db, songs); err != nil {
+		log.Printf("Error: Failed to get links of song %d: %v", newSong.ID, err)
+	}
+	newSong = songs[0]
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/GetJob?jobId=%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobId":    job.ID,
+		"song":     newSong,
+		"warnings": warnings,
+	})
+}
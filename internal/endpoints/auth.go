@@ -0,0 +1,22 @@
+package endpoints
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// userIDHeader identifies the caller until real authentication exists
+const userIDHeader = "X-User-Id"
+
+// adminTokenHeader must match ADMIN_TOKEN from .env for admin endpoints
+const adminTokenHeader = "X-Admin-Token"
+
+// isAdmin checks the admin token, admin endpoints are closed when ADMIN_TOKEN is empty
+func isAdmin(r *http.Request) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(adminTokenHeader)), []byte(token)) == 1
+}
@@ -0,0 +1,89 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"log"
+	"music_library_api/internal/charts"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// @Summary Получить чарт
+// @Description Возвращает заранее рассчитанный чарт: топ песен, топ исполнителей или трендовые песни за период
+// @Tags Charts
+// @Produce json
+// @Param chart query string false "Тип чарта: songs, artists, trending (по умолчанию songs)"
+// @Param period query string false "Период: day, week, month, all (по умолчанию week)"
+// @Param genre query string false "Фильтр по жанру"
+// @Param limit query int false "Количество позиций (по умолчанию 10)"
+// @Success 200 {object} map[string]interface{} "Чарт"
+// @Failure 400 {string} string "Неизвестный тип чарта или период"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/charts [get]
+func GetCharts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetCharts> endpoint...")
+
+	chart := r.URL.Query().Get("chart")
+	if chart == "" {
+		chart = charts.TopSongs
+	}
+	if chart != charts.TopSongs && chart != charts.TopArtists && chart != charts.Trending {
+		log.Printf("Error: Unknown chart %q", chart)
+		http.Error(w, "Unknown chart, use songs, artists or trending", http.StatusBadRequest)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+	if _, ok := charts.Periods[period]; !ok {
+		log.Printf("Error: Unknown period %q", period)
+		http.Error(w, "Unknown period, use day, week, month or all", http.StatusBadRequest)
+		return
+	}
+
+	genre := r.URL.Query().Get("genre")
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	log.Printf("Debug: Chart: %s, Period: %s, Genre: %q, Limit: %d", chart, period, genre, limit)
+
+	db := database.GetDB()
+	var entries []models.ChartEntry
+	err = db.Where("chart = ? AND period = ? AND genre = ?", chart, period, genre).
+		Order("rank").Limit(limit).Find(&entries).Error
+	if err != nil {
+		log.Printf("Error: Failed to find chart from DB: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var computedAt *time.Time
+	if len(entries) > 0 {
+		computedAt = &entries[0].ComputedAt
+	}
+
+	response := map[string]interface{}{
+		"chart":      chart,
+		"period":     period,
+		"genre":      genre,
+		"computedAt": computedAt,
+		"entries":    entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,469 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"music_library_api/internal/artwork"
+	"music_library_api/internal/blob"
+	"music_library_api/internal/database"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxImageSize = 10 << 20 // 10 MB
+
+// imageKey is the blob of the original (size 0) or a thumbnail
+func imageKey(sha string, size int) string {
+	if size == 0 {
+		return contentKey("images", sha)
+	}
+	return fmt.Sprintf("%s-%d", contentKey("images", sha), size)
+}
+
+func imageURL(sha string, size int) string {
+	if size == 0 {
+		return "/api/GetImage?hash=" + sha
+	}
+	return fmt.Sprintf("/api/GetImage?hash=%s&size=%d", sha, size)
+}
+
+func coverOf(img models.Image, fromAlbum bool) *models.Cover {
+	cover := &models.Cover{URL: imageURL(img.SHA256, 0), Thumbnails: map[string]string{}, FromAlbum: fromAlbum}
+	for _, size := range artwork.ThumbnailSizes {
+		cover.Thumbnails[strconv.Itoa(size)] = imageURL(img.SHA256, size)
+	}
+	return cover
+}
+
+// attachCovers fills Cover of songs, a song without its own cover gets the cover of its album
+func attachCovers(db *gorm.DB, songs []models.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	ids := make([]int, len(songs))
+	var albumKeys []string
+	for i, s := range songs {
+		ids[i] = s.ID
+		if s.Album != "" {
+			albumKeys = append(albumKeys, dedup.Key(s.Group, s.Album))
+		}
+	}
+
+	var own []struct {
+		SongID int
+		models.Image
+	}
+	err := db.Model(&models.SongCover{}).
+		Select("song_covers.song_id, images.*").
+		Joins("JOIN images ON images.id = song_covers.image_id").
+		Where("song_covers.song_id IN ?", ids).Scan(&own).Error
+	if err != nil {
+		return err
+	}
+	bySong := make(map[int]models.Image, len(own))
+	for _, c := range own {
+		bySong[c.SongID] = c.Image
+	}
+
+	byAlbum := map[string]models.Image{}
+	if len(albumKeys) > 0 {
+		var albums []struct {
+			AlbumKey string
+			models.Image
+		}
+		err := db.Model(&models.AlbumCover{}).
+			Select("album_covers.album_key, images.*").
+			Joins("JOIN images ON images.id = album_covers.image_id").
+			Where("album_covers.album_key IN ?", albumKeys).Scan(&albums).Error
+		if err != nil {
+			return err
+		}
+		for _, c := range albums {
+			byAlbum[c.AlbumKey] = c.Image
+		}
+	}
+
+	for i := range songs {
+		if img, ok := bySong[songs[i].ID]; ok {
+			songs[i].Cover = coverOf(img, false)
+		} else if img, ok := byAlbum[dedup.Key(songs[i].Group, songs[i].Album)]; ok && songs[i].Album != "" {
+			songs[i].Cover = coverOf(img, true)
+		}
+	}
+	return nil
+}
+
+// storeImage saves an image with its thumbnails, an already known content is reused as is
+func storeImage(ctx context.Context, db *gorm.DB, content io.ReadSeeker, size int64, sha, contentType string) (*models.Image, error) {
+	var img models.Image
+	err := db.Where("sha256 = ?", sha).First(&img).Error
+	if err == nil {
+		return &img, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	decoded, err := artwork.Decode(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := putBlob(ctx, imageKey(sha, 0), content, size, contentType); err != nil {
+		return nil, err
+	}
+	for _, side := range artwork.ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := artwork.EncodeJPEG(&buf, artwork.Thumbnail(decoded, side)); err != nil {
+			return nil, err
+		}
+		if err := blob.GetStore().Put(ctx, imageKey(sha, side), &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return nil, err
+		}
+	}
+
+	img = models.Image{
+		SHA256:      sha,
+		ContentType: contentType,
+		Width:       decoded.Bounds().Dx(),
+		Height:      decoded.Bounds().Dy(),
+		Size:        size,
+	}
+	// a parallel upload of the same file may have inserted it
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&img).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("sha256 = ?", sha).First(&img).Error; err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// errImageReleased is returned by linkImage when the image was deleted before it was linked
+var errImageReleased = errors.New("image was released")
+
+// linkImage stores an image and runs link in a transaction holding the image row, so releaseImage
+// can not delete it in between; an image released right before is stored again
+func linkImage(ctx context.Context, db *gorm.DB, content io.ReadSeeker, size int64, sha, contentType string,
+	link func(tx *gorm.DB, img *models.Image) error) (*models.Image, error) {
+	for attempt := 0; ; attempt++ {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		img, err := storeImage(ctx, db, content, size, sha, contentType)
+		if err != nil {
+			return nil, err
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id").First(&models.Image{}, img.ID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errImageReleased
+			}
+			if err != nil {
+				return err
+			}
+			return link(tx, img)
+		})
+		if errors.Is(err, errImageReleased) && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
+}
+
+// releaseImage deletes an image and its blobs when no cover uses it. The row is locked, so covers
+// linked meanwhile by linkImage are counted; blobs go before the commit, an upload of the same
+// content finds the row until they are gone and stores them again after
+func releaseImage(ctx context.Context, db *gorm.DB, imageId int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var img models.Image
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&img, imageId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) { // released by a parallel request
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var songs, albums int64
+		if err := tx.Model(&models.SongCover{}).Where("image_id = ?", imageId).Count(&songs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AlbumCover{}).Where("image_id = ?", imageId).Count(&albums).Error; err != nil {
+			return err
+		}
+		if songs+albums > 0 {
+			return nil
+		}
+		if err := tx.Delete(&img).Error; err != nil {
+			return err
+		}
+		for _, size := range append([]int{0}, artwork.ThumbnailSizes...) {
+			if err := blob.GetStore().Delete(ctx, imageKey(img.SHA256, size)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// coverTarget reads songId or group and album of a cover request, a non-empty message is the error response
+func coverTarget(db *gorm.DB, r *http.Request) (songId int, album *models.AlbumCover, status int, message string) {
+	q := r.URL.Query()
+	if v := q.Get("songId"); v != "" {
+		songId, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, nil, http.StatusBadRequest, "Invalid songId parameter"
+		}
+		var song models.Song
+		if err := db.First(&song, songId).Error; err != nil {
+			return 0, nil, http.StatusNotFound, "Song not found"
+		}
+		return songId, nil, 0, ""
+	}
+	group, name := strings.TrimSpace(q.Get("group")), strings.TrimSpace(q.Get("album"))
+	if group == "" || name == "" {
+		return 0, nil, http.StatusBadRequest, "Specify songId or group and album"
+	}
+	return 0, &models.AlbumCover{AlbumKey: dedup.Key(group, name), Group: group, Album: name}, 0, ""
+}
+
+// @Summary Загрузить обложку
+// @Description Сохраняет обложку песни (songId) или альбома (group и album) в формате JPEG, PNG или WebP и создает уменьшенные копии 64, 300 и 600 пикселей. Одинаковые изображения хранятся один раз. Обложка альбома показывается у песен альбома без своей обложки
+// @Description Ссылки на обложку возвращаются в поле cover песен
+// @Tags Covers
+// @Accept octet-stream
+// @Accept mpfd
+// @Produce json
+// @Param songId query int false "ID песни"
+// @Param group query string false "Группа альбома"
+// @Param album query string false "Название альбома"
+// @Param body body string true "Изображение (или поле file формы multipart)"
+// @Success 201 {object} map[string]interface{} "image - models.Image, cover - ссылки на изображение и уменьшенные копии"
+// @Failure 400 {string} string "Некорректные параметры или поврежденное изображение"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST или PUT)"
+// @Failure 413 {string} string "Файл больше 10 МБ"
+// @Failure 415 {string} string "Файл не JPEG, PNG или WebP"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/UploadCover [post]
+func UploadCover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		log.Printf("Error: Invalid request method. Expected POST or PUT, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <UploadCover> endpoint...")
+
+	db := database.GetDB()
+	songId, album, status, message := coverTarget(db, r)
+	if message != "" {
+		log.Printf("Error: %s", message)
+		http.Error(w, message, status)
+		return
+	}
+
+	u, err := receiveUpload(w, r, maxImageSize)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Error: Image is larger than %d bytes", maxImageSize)
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error: Failed to receive image: %v", err)
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer u.Close()
+
+	contentType := artwork.Sniff(u.head)
+	if contentType == "" {
+		log.Printf("Error: Uploaded file is not an image (%s)", http.DetectContentType(u.head))
+		http.Error(w, artwork.ErrUnsupported.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	// the replaced image is released after the new one is linked
+	var previous []int
+	img, err := linkImage(r.Context(), db, u.file, u.size, u.sha256, contentType, func(tx *gorm.DB, img *models.Image) error {
+		previous = nil
+		if album == nil {
+			cover := models.SongCover{SongID: songId, ImageID: img.ID}
+			if err := tx.Model(&models.SongCover{}).Where("song_id = ?", songId).Pluck("image_id", &previous).Error; err != nil {
+				return err
+			}
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cover).Error
+		}
+		album.ImageID = img.ID
+		if err := tx.Model(&models.AlbumCover{}).Where("album_key = ?", album.AlbumKey).Pluck("image_id", &previous).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(album).Error
+	})
+	if err != nil {
+		if errors.Is(err, artwork.ErrUnsupported) || errors.Is(err, artwork.ErrInvalid) {
+			log.Printf("Error: Invalid image: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error: Failed to save cover: %v", err)
+		http.Error(w, "Error saving cover: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(previous) > 0 && previous[0] != img.ID {
+		if err := releaseImage(r.Context(), db, previous[0]); err != nil {
+			log.Printf("Error: Failed to delete replaced image %d: %v", previous[0], err)
+		}
+	}
+	log.Printf("Info: Cover image %d (%dx%d %s) saved", img.ID, img.Width, img.Height, img.ContentType)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"image": img,
+		"cover": coverOf(*img, album != nil),
+	})
+}
+
+// @Summary Удалить обложку
+// @Description Удаляет обложку песни (songId) или альбома (group и album)
+// @Tags Covers
+// @Produce json
+// @Param songId query int false "ID песни"
+// @Param group query string false "Группа альбома"
+// @Param album query string false "Название альбома"
+// @Success 200 {object} map[string]interface{} "deleted: true"
+// @Failure 400 {string} string "Некорректные параметры"
+// @Failure 404 {string} string "Обложка не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Failure 500 {string} string "Ошибка сервера при удалении"
+// @Router /api/DeleteCover [delete]
+func DeleteCover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteCover> endpoint...")
+
+	db := database.GetDB()
+	songId, album, status, message := coverTarget(db, r)
+	if message != "" {
+		log.Printf("Error: %s", message)
+		http.Error(w, message, status)
+		return
+	}
+
+	var deleted []int // image of the cover
+	var res *gorm.DB
+	if album == nil {
+		res = db.Model(&models.SongCover{}).Where("song_id = ?", songId).Pluck("image_id", &deleted)
+		if res.Error == nil {
+			res = db.Where("song_id = ?", songId).Delete(&models.SongCover{})
+		}
+	} else {
+		res = db.Model(&models.AlbumCover{}).Where("album_key = ?", album.AlbumKey).Pluck("image_id", &deleted)
+		if res.Error == nil {
+			res = db.Where("album_key = ?", album.AlbumKey).Delete(&models.AlbumCover{})
+		}
+	}
+	if res.Error != nil {
+		log.Printf("Error: Failed to delete cover: %v", res.Error)
+		http.Error(w, "Failed to delete cover", http.StatusInternalServerError)
+		return
+	}
+	if len(deleted) == 0 {
+		log.Println("Error: Cover not found")
+		http.Error(w, "Cover not found", http.StatusNotFound)
+		return
+	}
+	if err := releaseImage(r.Context(), db, deleted[0]); err != nil {
+		log.Printf("Error: Failed to delete image %d: %v", deleted[0], err)
+	}
+	log.Printf("Info: Cover image %d unlinked", deleted[0])
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deleted": true})
+}
+
+// @Summary Изображение
+// @Description Отдает изображение обложки или его уменьшенную копию (JPEG). Адрес содержит хеш содержимого, поэтому ответ кешируется навсегда
+// @Tags Covers
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/webp
+// @Param hash query string true "SHA-256 изображения"
+// @Param size query int false "64, 300 или 600 - уменьшенная копия, без параметра - оригинал"
+// @Success 200 {file} file "Изображение"
+// @Success 304 {string} string "Изображение не изменилось"
+// @Failure 400 {string} string "Некорректные параметры"
+// @Failure 404 {string} string "Изображение не найдено"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET или HEAD)"
+// @Router /api/GetImage [get]
+func GetImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		log.Printf("Error: Invalid request method. Expected GET or HEAD, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetImage> endpoint...")
+
+	hash := strings.ToLower(r.URL.Query().Get("hash"))
+	if raw, err := hex.DecodeString(hash); err != nil || len(raw) != 32 {
+		log.Printf("Error: Invalid hash parameter %q", hash)
+		http.Error(w, "Invalid hash parameter", http.StatusBadRequest)
+		return
+	}
+	size := 0
+	if v := r.URL.Query().Get("size"); v != "" {
+		size, _ = strconv.Atoi(v)
+		known := false
+		for _, s := range artwork.ThumbnailSizes {
+			known = known || s == size
+		}
+		if !known {
+			log.Printf("Error: Invalid size parameter %q", v)
+			http.Error(w, "Invalid size parameter, use 64, 300 or 600", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db := database.GetDB()
+	var img models.Image
+	if err := db.Where("sha256 = ?", hash).First(&img).Error; err != nil {
+		log.Printf("Error: Image %s not found", hash)
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	key, contentType, length := imageKey(img.SHA256, size), img.ContentType, img.Size
+	if size != 0 {
+		info, err := blob.GetStore().Stat(r.Context(), key)
+		if err != nil {
+			log.Printf("Error: Thumbnail %s is missing: %v", key, err)
+			http.Error(w, "Image not found", http.StatusNotFound)
+			return
+		}
+		contentType, length = "image/jpeg", info.Size
+	}
+
+	content := blob.NewReadSeeker(r.Context(), blob.GetStore(), key, length)
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%d"`, img.SHA256, size))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", img.CreatedAt, content)
+}
@@ -0,0 +1,134 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// handleDuplicate answers AddSong when the song is already stored
+func handleDuplicate(w http.ResponseWriter, db *gorm.DB, existing *models.Song, incoming models.Song, manual bool, onDuplicate string) {
+	log.Printf("Info: Song %q - %q duplicates song %d, onDuplicate=%s", incoming.Group, incoming.Song, existing.ID, onDuplicate)
+
+	response := map[string]interface{}{
+		"duplicate":  true,
+		"existingId": existing.ID,
+	}
+
+	switch onDuplicate {
+	case ingest.OnDuplicateSkip:
+		response["song"] = existing
+
+	case ingest.OnDuplicateUpdate:
+		job, err := ingest.UpdateDuplicate(db, existing, incoming, manual)
+		if err != nil {
+			log.Printf("Error: Failed to update song %d: %v", existing.ID, err)
+			http.Error(w, "Failed to update song", http.StatusInternalServerError)
+			return
+		}
+		response["song"] = existing
+		if job != nil {
+			response["jobId"] = job.ID
+			w.Header().Set("Location", fmt.Sprintf("/api/GetJob?jobId=%d", job.ID))
+		}
+
+	default:
+		response["error"] = "song already exists"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict) // code 409
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Возможные дубликаты
+// @Description Ищет в библиотеке пары песен одного исполнителя, которые вероятно являются одной песней: одинаковый ключ (старые записи), одинаковое название без версии ("Creep (Acoustic)", "Creep - Live") или похожее название
+// @Tags Songs
+// @Produce json
+// @Param threshold query number false "Минимальная похожесть названий от 0 до 1 (по умолчанию 0.85)"
+// @Param page query int false "Номер страницы (по умолчанию 1)"
+// @Param limit query int false "Количество пар на странице (по умолчанию 20)"
+// @Success 200 {object} map[string]interface{} "Пары песен с похожестью и причиной (key, version, title)"
+// @Failure 400 {string} string "Некорректный threshold"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка чтения из БД"
+// @Router /api/GetDuplicates [get]
+func GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetDuplicates> endpoint...")
+
+	threshold := 0.85
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t > 1 {
+			log.Printf("Error: Invalid threshold parameter %q", v)
+			http.Error(w, "Invalid threshold parameter, expected number in (0, 1]", http.StatusBadRequest)
+			return
+		}
+		threshold = t
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	db := database.GetDB()
+	var songs []models.Song
+	if err := db.Select("id", "group_name", "song").Find(&songs).Error; err != nil {
+		log.Printf("Error: Failed to load songs: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]dedup.Entry, len(songs))
+	byID := make(map[int]models.Song, len(songs))
+	for i, s := range songs {
+		entries[i] = dedup.Entry{ID: s.ID, Group: s.Group, Song: s.Song}
+		byID[s.ID] = s
+	}
+	pairs := dedup.NearDuplicates(entries, threshold)
+
+	type duplicate struct {
+		dedup.Pair
+		GroupA string `json:"groupA"`
+		SongA  string `json:"songA"`
+		GroupB string `json:"groupB"`
+		SongB  string `json:"songB"`
+	}
+	result := []duplicate{}
+	start := (page - 1) * limit
+	for i := start; i < len(pairs) && i < start+limit; i++ {
+		p := pairs[i]
+		a, b := byID[p.A], byID[p.B]
+		result = append(result, duplicate{Pair: p, GroupA: a.Group, SongA: a.Song, GroupB: b.Group, SongB: b.Song})
+	}
+
+	response := map[string]interface{}{
+		"duplicates": result,
+		"page":       page,
+		"perPage":    limit,
+		"total":      len(pairs),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -5,15 +5,19 @@ import (
 	"fmt"
 	"log"
 	"music_library_api/internal/database"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/lyrics"
 	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
 	"net/http"
-	"net/url"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
 )
 
 // @Summary Получить список песен
@@ -22,7 +26,10 @@ import (
 // @Produce json
 // @Param group query string false "Фильтр по названию группы"
 // @Param song query string false "Фильтр по названию песни"
+// @Param genre query string false "Фильтр по жанру"
+// @Param status query string false "Фильтр по статусу обогащения: pending, enriched, failed"
 // @Param page query int false "Номер страницы (по умолчанию 1)"
+// @Param sort query string false "Сортировка: rating - по средней оценке"
 // @Success 200 {array} models.Song "Список найденных песен"
 // @Failure 400 {string} string "Некорректный запрос (например, если страница вне диапазона)"
 // @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
@@ -31,7 +38,10 @@ func GetSongs(w http.ResponseWriter, r *http.Request) {
 	log.Println("Info: <GetSongs> endpoint...")
 	group := r.URL.Query().Get("group")
 	song := r.URL.Query().Get("song")
+	genre := r.URL.Query().Get("genre")
+	status := r.URL.Query().Get("status")
 	pageStr := r.URL.Query().Get("page")
+	sortBy := r.URL.Query().Get("sort")
 
 	//default
 	page := 1
@@ -69,11 +79,32 @@ func GetSongs(w http.ResponseWriter, r *http.Request) {
 		if song != "" && !strings.Contains(strings.ToLower(s.Song), strings.ToLower(song)) {
 			continue
 		}
+		// genre sort
+		if genre != "" && !strings.EqualFold(s.Genre, genre) {
+			continue
+		}
+		// status sort
+		if status != "" && s.Status != status {
+			continue
+		}
 
 		filteredSongs = append(filteredSongs, s)
 	}
 
 	log.Printf("Debug: Filtered %d songs based on query parameters", len(filteredSongs))
+
+	if err := attachRatings(db, filteredSongs); err != nil {
+		log.Printf("Error: Failed to get ratings from DB: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if sortBy == "rating" { // best rated first, unrated last
+		sort.SliceStable(filteredSongs, func(i, j int) bool {
+			return filteredSongs[i].RatingAvg > filteredSongs[j].RatingAvg
+		})
+	}
+
 	index_song_start := (page - 1) * limit
 	index_song_end := index_song_start + limit
 
@@ -88,21 +119,36 @@ func GetSongs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Debug: Returning songs from index %d to %d", index_song_start, index_song_end)
+	pageSongs := filteredSongs[index_song_start:index_song_end]
+	if err := attachCovers(db, pageSongs); err != nil {
+		log.Printf("Error: Failed to get covers from DB: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := attachLinks(db, pageSongs); err != nil {
+		log.Printf("Error: Failed to get links from DB: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(filteredSongs[index_song_start:index_song_end])
+	json.NewEncoder(w).Encode(pageSongs)
 }
 
 // @Summary Добавить песню
-// @Description Добавляет новую песню в базу данных, предварительно получая дополнительную информацию с внешнего API
+// @Description Принимает новую песню сразу (статус pending) и ставит задачу на получение дополнительной информации от провайдера метаданных (см. METADATA_PROVIDERS). Статус задачи - /api/GetJob.
+// @Description По умолчанию releaseDate, text и link берутся только из провайдера. В режиме mode=manual поля клиента проверяются и сохраняются, а данные провайдера заполняют пустые поля или заменяют их по политике ENRICHMENT_PRECEDENCE; если провайдер не знает песню, она остается со статусом manual
 // @Tags Songs
 // @Accept json
 // @Produce json
-// @Param song body models.Song true "Данные о песне"
-// @Success 201 {object} models.Song "Песня успешно добавлена"
-// @Failure 400 {string} string "Некорректный JSON-запрос"
-// @Failure 404 {string} string "Информация о песне не найдена во внешнем API"
+// @Param song body addSongRequest true "Данные о песне"
+// @Param mode query string false "manual - принять поля клиента"
+// @Param onDuplicate query string false "Если такая песня уже есть (без учета регистра, пунктуации, The и feat.): error - 409 (по умолчанию), skip - вернуть существующую, update - обновить существующую"
+// @Success 200 {object} map[string]interface{} "Песня уже существует (skip, update), duplicate=true"
+// @Success 202 {object} map[string]interface{} "Песня принята, jobId - ID задачи обогащения"
+// @Failure 400 {string} string "Некорректный JSON-запрос или некорректные поля"
+// @Failure 409 {object} map[string]interface{} "Песня уже существует, existingId - ее ID"
 // @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
-// @Failure 500 {string} string "Ошибка сервера при обращении к API или сохранении в БД"
+// @Failure 500 {string} string "Ошибка сервера при сохранении в БД"
 // @Router /api/AddSong [post]
 func AddSongs(w http.ResponseWriter, r *http.Request) {
 
@@ -114,78 +160,82 @@ func AddSongs(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("Info: <AddSongs> endpoint...")
 
-	var newSong models.Song
-	err := json.NewDecoder(r.Body).Decode(&newSong)
-	if err != nil {
-		log.Printf("Error: Failed to decode JSON body: %v", err)
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "manual" {
+		log.Printf("Error: Unknown mode %q", mode)
+		http.Error(w, "Unknown mode, use manual", http.StatusBadRequest)
 		return
 	}
-	log.Printf("Debug: Incoming endpoint request body %+v", newSong)
-
-	apiURL := fmt.Sprintf("%s/info?group=%s&song=%s", os.Getenv("API_URL"),
-		url.QueryEscape(newSong.Group), url.QueryEscape(newSong.Song)) // url format
+	manual := mode == "manual"
 
-	resp, err := http.Get(apiURL) // REQUEST to your SWAGGER API
+	onDuplicate := r.URL.Query().Get("onDuplicate")
+	switch onDuplicate {
+	case "":
+		onDuplicate = ingest.OnDuplicateError
+	case ingest.OnDuplicateError, ingest.OnDuplicateSkip, ingest.OnDuplicateUpdate:
+	default:
+		log.Printf("Error: Unknown onDuplicate %q", onDuplicate)
+		http.Error(w, "Unknown onDuplicate, use skip, update or error", http.StatusBadRequest)
+		return
+	}
 
+	var req addSongRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		log.Printf("Error: Failed to fetch song info from swagger api: %v", err)
-		http.Error(w, "Failed to fetch song info from swagger api", http.StatusInternalServerError)
+		log.Printf("Error: Failed to decode JSON body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
-	defer resp.Body.Close() // close after beginning request
+	log.Printf("Debug: Incoming endpoint request body %+v", req)
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Error: Song info not found, received status code %d", resp.StatusCode)
-		http.Error(w, "Song info not found", http.StatusNotFound)
+	newSong, err := req.toSong(manual)
+	if err != nil {
+		log.Printf("Error: Invalid song: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	//struct for answer swagger api
-	var songInfo struct {
-		ReleaseDate string `json:"releaseDate"`
-		Text        string `json:"text"`
-		Link        string `json:"link"`
-	}
+	db := database.GetDB()
 
-	err = json.NewDecoder(resp.Body).Decode(&songInfo) // get response params
+	existing, err := ingest.FindDuplicate(db, newSong.Group, newSong.Song)
 	if err != nil {
-		log.Printf("Error: Failed to decode API response: %v", err)
-		http.Error(w, "Invalid API response", http.StatusInternalServerError)
+		log.Printf("Error: Failed to check duplicates: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		handleDuplicate(w, db, existing, newSong, manual, onDuplicate)
 		return
 	}
 
-	log.Printf("Debug: Incoming request body from swagger api (/Info) %+v", songInfo)
-
-	parsedDate, err := time.Parse("02.01.2006", songInfo.ReleaseDate) // Output date format from api swagger
+	// song is saved as pending, info is fetched by ingest workers
+	job, err := ingest.Enqueue(db, &newSong, manual)
 	if err != nil {
-		log.Printf("Error: Invalid date format from API: %v", err)
-		http.Error(w, "Invalid date format from API", http.StatusInternalServerError)
+		// the same song could be added by a parallel request, unique index stops it
+		if existing, _ := ingest.FindDuplicate(db, newSong.Group, newSong.Song); existing != nil {
+			handleDuplicate(w, db, existing, newSong, manual, onDuplicate)
+			return
+		}
+		log.Printf("Error: Failed to save song to database: %v", err)
+		http.Error(w, "Error saving song to database: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	log.Printf("Info: Song %d accepted, enrichment job %d queued", newSong.ID, job.ID)
 
-	newSong.ReleaseDate = parsedDate
-	newSong.Text = songInfo.Text
-	newSong.Link = songInfo.Link
-
-	db := database.GetDB()
-
-	log.Printf("Debug: This will be added: %+v", songInfo)
-	result_err := db.Create(&newSong) //added to db
-
-	if result_err.Error != nil { //check errors
-		log.Printf("Error: Failed to save song to database: %v", result_err.Error)
-		http.Error(w, "Error saving song to database: "+result_err.Error.Error(), http.StatusInternalServerError)
-		return
+	response := map[string]interface{}{
+		"jobId": job.ID,
+		"song":  newSong,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated) // code 201
-	json.NewEncoder(w).Encode(newSong)
+	w.Header().Set("Location", fmt.Sprintf("/api/GetJob?jobId=%d", job.ID))
+	w.WriteHeader(http.StatusAccepted) // code 202
+	json.NewEncoder(w).Encode(response)
 }
 
 // @Summary Удалить песню
 // @Description Удаляет песню из базы данных по её ID
+// @Description Вместе с песней удаляются ее аудиофайл, обложка и ссылки
 // @Tags Songs
 // @Produce json
 // @Param songId query int true "ID песни для удаления"
@@ -235,11 +285,42 @@ func DeleteSong(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Info: Found song to delete: %+v", song)
-	if err := db.Delete(&song).Error; err != nil {
+	var audioKeys []string
+	var imageIds []int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AudioAsset{}).Where("song_id = ?", song.ID).Pluck("key", &audioKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("song_id = ?", song.ID).Delete(&models.AudioAsset{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SongCover{}).Where("song_id = ?", song.ID).Pluck("image_id", &imageIds).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("song_id = ?", song.ID).Delete(&models.SongCover{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("song_id = ?", song.ID).Delete(&models.SongLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&song).Error
+	})
+	if err != nil {
 		log.Printf("Error: Failed to delete song with id %d: error: %v", songIntId, err)
 		http.Error(w, "Failed to delete song", http.StatusInternalServerError)
 		return
 	}
+	// the rows are gone, a failed blob delete only leaves an unused file
+	for _, key := range audioKeys {
+		if err := releaseBlob(r.Context(), db, &models.AudioAsset{}, key); err != nil {
+			log.Printf("Error: Failed to delete audio file %s: %v", key, err)
+		}
+	}
+	for _, imageId := range imageIds {
+		if err := releaseImage(r.Context(), db, imageId); err != nil {
+			log.Printf("Error: Failed to delete image %d: %v", imageId, err)
+		}
+	}
 
 	log.Printf("Info: Song with id %d deleted successfully", songIntId)
 	w.Header().Set("Content-Type", "application/json")
@@ -258,6 +339,7 @@ func DeleteSong(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {string} string "Некорректный ID или параметры запроса"
 // @Failure 404 {string} string "Песня не найдена"
 // @Failure 405 {string} string "Неверный метод запроса (требуется PUT)"
+// @Failure 409 {object} map[string]interface{} "Песня с такими group и song уже есть, existingId - ее ID"
 // @Failure 500 {string} string "Ошибка сервера при обновлении"
 // @Router /api/EditSong [put]
 func EditSong(w http.ResponseWriter, r *http.Request) {
@@ -320,55 +402,123 @@ func EditSong(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Debug: Incoming request body: %+v", requestData)
 
+	oldText, oldLink := song.Text, song.Link
+	edited := map[string]string{} // provenance of enriched fields
+
 	// update
 	for _, field := range params {
+		switch field {
+		case ingest.FieldReleaseDate, ingest.FieldText, ingest.FieldLink:
+			edited[field] = ingest.SourceEdit
+		}
 		switch field {
 		case "group":
 			song.Group = requestData["group"]
 		case "song":
 			song.Song = requestData["song"]
+		case "genre":
+			song.Genre = requestData["genre"]
+		case "album":
+			song.Album = requestData["album"]
 		case "releaseDate":
-			releaseDate, err := time.Parse("2006-01-02", requestData["releaseDate"])
+			releaseDate, precision, err := releasedate.Parse(requestData["releaseDate"])
 			if err != nil {
-				log.Printf("Error: Invalid releaseDate format. Expected YYYY-MM-DD: %v", err)
-				http.Error(w, "Invalid releaseDate format. Use YYYY-MM-DD", http.StatusBadRequest)
+				log.Printf("Error: Invalid releaseDate format: %v", err)
+				http.Error(w, "Invalid releaseDate format. Use YYYY-MM-DD, YYYY-MM or YYYY", http.StatusBadRequest)
 				return
 			}
 			song.ReleaseDate = releaseDate
+			song.ReleaseDatePrecision = precision
 		case "text":
 			song.Text = requestData["text"]
 		case "link":
-			song.Link = requestData["link"]
+			link, err := canonicalLink(requestData["link"])
+			if err != nil {
+				log.Printf("Error: Invalid link: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			song.Link = link
 		}
 	}
 
+	// renamed song must not collide with another one
+	existing, err := ingest.FindDuplicate(db, song.Group, song.Song)
+	if err != nil {
+		log.Printf("Error: Failed to check duplicates: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil && existing.ID != song.ID {
+		handleDuplicate(w, db, existing, song, false, ingest.OnDuplicateError)
+		return
+	}
+
 	// new update time
 	song.UpdatedAt = time.Now()
 
-	// save to db
-	if err := db.Save(&song).Error; err != nil {
+	// save to db, annotations follow the changed text
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&song).Error; err != nil {
+			return err
+		}
+		if err := ingest.SaveProvenance(tx, song.ID, edited); err != nil {
+			return err
+		}
+		// the edited link replaces the old one in the links of the song
+		if song.Link != oldLink && oldLink != "" {
+			if err := tx.Where("song_id = ? AND url = ?", song.ID, oldLink).Delete(&models.SongLink{}).Error; err != nil {
+				return err
+			}
+			link, err := syncLegacyLink(tx, song.ID, oldLink)
+			if err != nil {
+				return err
+			}
+			song.Link = link
+		}
+		if song.Text != oldText {
+			return ingest.ReanchorAnnotations(tx, song)
+		}
+		return nil
+	})
+	if err != nil {
 		log.Printf("Error: Failed to update song with id %d: %v", songIntId, err)
 		http.Error(w, "Failed to update song", http.StatusInternalServerError)
 		return
 	}
 
 	log.Printf("Info: Song with id %d updated successfully", songIntId)
+	songs := []models.Song{song}
+	if err := attachRatings(db, songs); err != nil {
+		log.Printf("Error: Failed to get rating of song %d: %v", songIntId, err)
+	}
+	if err := attachCovers(db, songs); err != nil {
+		log.Printf("Error: Failed to get cover of song %d: %v", songIntId, err)
+	}
+	if err := attachLinks(db, songs); err != nil {
+		log.Printf("Error: Failed to get links of song %d: %v", songIntId, err)
+	}
+	song = songs[0]
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(song)
 }
 
 // @Summary Получить текст песни постранично
-// @Description Возвращает текст песни, разбитый на страницы по указанному лимиту строк
+// @Description Возвращает текст песни, разбитый на страницы по указанному лимиту строк, вместе с аннотациями к строкам (verse - абсолютный номер строфы)
 // @Tags Songs
 // @Accept json
 // @Produce json
 // @Param songId query int true "ID песни"
 // @Param page query int false "Номер страницы (по умолчанию 1)"
 // @Param limit query int false "Количество строф на странице (по умолчанию 2)"
+// @Param lang query string false "Язык перевода (BCP-47). Если перевода нет, возвращается оригинал и fallback=true"
+// @Param mode query string false "side-by-side - оригинал и перевод рядом, выровненные по номеру строфы"
 // @Success 200 {object} map[string]interface{} "Текст песни постранично"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
 // @Failure 400 {string} string "Некорректные параметры запроса"
 // @Failure 404 {string} string "Песня не найдена или страница вне диапазона"
 // @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера"
 // @Router /api/GetSongText [get]
 func GetSongText(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -404,7 +554,19 @@ func GetSongText(w http.ResponseWriter, r *http.Request) {
 		limit = 2
 	}
 
-	log.Printf("Debug: Page: %d, Limit: %d", page, limit)
+	lang := r.URL.Query().Get("lang")
+	var requested language.Tag
+	if lang != "" {
+		requested, err = language.Parse(lang)
+		if err != nil {
+			log.Printf("Error: Invalid lang parameter %q: %v", lang, err)
+			http.Error(w, "Invalid lang parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	sideBySide := r.URL.Query().Get("mode") == "side-by-side"
+
+	log.Printf("Debug: Page: %d, Limit: %d, Lang: %q, Side by side: %t", page, limit, lang, sideBySide)
 
 	if page <= 0 {
 		page = 1
@@ -416,6 +578,9 @@ func GetSongText(w http.ResponseWriter, r *http.Request) {
 	var song models.Song
 
 	if err := db.First(&song, songIntId).Error; err != nil {
+		if redirectMerged(w, r, db, songIntId) {
+			return
+		}
 		log.Printf("Error: Song with id %d not found", songIntId)
 		http.Error(w, "Song not found", http.StatusNotFound)
 		return
@@ -424,28 +589,97 @@ func GetSongText(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Info: Found song with id %d: %+v", songIntId, song)
 
 	//split text
-	verses := strings.Split(song.Text, "\n\n")
+	verses := lyrics.Verses(song.Text)
+
+	// translated text, falls back to the original when there is no translation
+	servedLang := ""
+	var translated []string
+	if lang != "" {
+		translation, err := findTranslation(db, song.ID, requested)
+		if err != nil {
+			log.Printf("Error: Failed to find translation %q of song %d: %v", lang, song.ID, err)
+			http.Error(w, "Failed to get translation", http.StatusInternalServerError)
+			return
+		}
+		if translation != nil {
+			servedLang = translation.Lang
+			translated = lyrics.Verses(translation.Text)
+		} else {
+			log.Printf("Info: No %s translation of song %d, returning original", lang, song.ID)
+		}
+	}
+
+	total := len(verses)
+	switch {
+	case translated != nil && !sideBySide:
+		total = len(translated)
+	case translated != nil && len(translated) > total: // side by side, longer translation
+		total = len(translated)
+	}
 
 	start := (page - 1) * limit
 	end := start + limit
 
-	if start >= len(verses) {
+	if start >= total {
 		log.Println("Error: Page out of range")
 		http.Error(w, "Page out of range", http.StatusNotFound)
 		return
 	}
-	if end > len(verses) {
-		end = len(verses)
+	if end > total {
+		end = total
+	}
+
+	var pageVerses interface{}
+	switch {
+	case translated != nil && sideBySide:
+		aligned := make([]alignedVerse, 0, end-start)
+		for i := start; i < end; i++ {
+			v := alignedVerse{Index: i}
+			if i < len(verses) {
+				v.Original = verses[i]
+			}
+			if i < len(translated) {
+				v.Translation = translated[i]
+			}
+			aligned = append(aligned, v)
+		}
+		pageVerses = aligned
+	case translated != nil:
+		pageVerses = translated[start:end]
+	default:
+		pageVerses = verses[start:end]
+	}
+
+	// annotations of returned verses and the ones lost after text edits,
+	// anchored to the original text so skipped for a translation alone
+	annotations := []models.Annotation{}
+	var orphaned []models.Annotation
+	if translated == nil || sideBySide {
+		if err := db.Where("song_id = ? AND orphaned = ? AND verse >= ? AND verse < ?", song.ID, false, start, end).
+			Order("verse, line, start_char").Find(&annotations).Error; err != nil {
+			log.Printf("Error: Failed to find annotations: %v", err)
+			http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := db.Where("song_id = ? AND orphaned = ?", song.ID, true).Find(&orphaned).Error; err != nil {
+			log.Printf("Error: Failed to find orphaned annotations: %v", err)
+			http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	// return verses
 	response := map[string]interface{}{
-		"song":    song.Song,
-		"group":   song.Group,
-		"verses":  verses[start:end],
-		"page":    page,
-		"perPage": limit,
-		"total":   len(verses),
+		"song":                song.Song,
+		"group":               song.Group,
+		"lang":                servedLang,
+		"fallback":            lang != "" && translated == nil,
+		"verses":              pageVerses,
+		"annotations":         annotations,
+		"orphanedAnnotations": orphaned,
+		"page":                page,
+		"perPage":             limit,
+		"total":               total,
 	}
 	log.Printf("Info: Returning verses: %v from page %d", response["verses"], page)
 
@@ -0,0 +1,157 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"log"
+	"music_library_api/internal/enrichment"
+	"net/http"
+	"strconv"
+)
+
+// @Summary Метрики внешнего API
+// @Description Количество запросов к внешнему API обогащения, доля ошибок, задержки и состояние circuit breaker
+// @Tags Enrichment
+// @Produce json
+// @Success 200 {object} enrichment.MetricsSnapshot "Метрики"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Router /api/EnrichmentMetrics [get]
+func EnrichmentMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <EnrichmentMetrics> endpoint...")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrichment.GetMetrics())
+}
+
+// @Summary Кэш ответов внешнего API
+// @Description Показывает записи кэша обогащения (в памяти и в БД). С параметрами group и song возвращает одну запись. Только для администратора
+// @Tags Enrichment
+// @Produce json
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Param group query string false "Название группы"
+// @Param song query string false "Название песни"
+// @Param limit query int false "Максимум записей (по умолчанию 100)"
+// @Success 200 {object} map[string]interface{} "Записи кэша"
+// @Failure 403 {string} string "Требуются права администратора"
+// @Failure 404 {string} string "Запись не найдена или кэш выключен"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка чтения из БД"
+// @Router /api/GetEnrichmentCache [get]
+func GetEnrichmentCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetEnrichmentCache> endpoint...")
+
+	if !isAdmin(r) {
+		log.Println("Error: GetEnrichmentCache called without admin token")
+		http.Error(w, "Admin rights required", http.StatusForbidden)
+		return
+	}
+
+	cache := enrichment.GetCache()
+	if cache == nil {
+		log.Println("Error: Enrichment cache is disabled")
+		http.Error(w, "Enrichment cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	group := r.URL.Query().Get("group")
+	song := r.URL.Query().Get("song")
+	if group != "" || song != "" {
+		entry := cache.Lookup(group, song)
+		if entry == nil {
+			log.Printf("Error: No cache entry for %q - %q", group, song)
+			http.Error(w, "Cache entry not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	memory, stored, err := cache.Entries(limit)
+	if err != nil {
+		log.Printf("Error: Failed to list stored cache entries: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"memory": memory,
+		"stored": stored,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Сброс кэша внешнего API
+// @Description Удаляет запись кэша обогащения для group и song, либо весь кэш при all=true. Только для администратора
+// @Tags Enrichment
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Param group query string false "Название группы"
+// @Param song query string false "Название песни"
+// @Param all query bool false "Очистить весь кэш"
+// @Success 204 "Кэш сброшен"
+// @Failure 400 {string} string "Не указаны group и song"
+// @Failure 403 {string} string "Требуются права администратора"
+// @Failure 404 {string} string "Кэш выключен"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Failure 500 {string} string "Ошибка удаления кэша"
+// @Router /api/DeleteEnrichmentCache [delete]
+func DeleteEnrichmentCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteEnrichmentCache> endpoint...")
+
+	if !isAdmin(r) {
+		log.Println("Error: DeleteEnrichmentCache called without admin token")
+		http.Error(w, "Admin rights required", http.StatusForbidden)
+		return
+	}
+
+	cache := enrichment.GetCache()
+	if cache == nil {
+		log.Println("Error: Enrichment cache is disabled")
+		http.Error(w, "Enrichment cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+	group := r.URL.Query().Get("group")
+	song := r.URL.Query().Get("song")
+
+	var err error
+	switch {
+	case all:
+		err = cache.InvalidateAll()
+	case group != "" && song != "":
+		err = cache.Invalidate(group, song)
+	default:
+		log.Println("Error: group and song or all=true are required")
+		http.Error(w, "group and song or all=true are required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error: Failed to invalidate cache: %v", err)
+		http.Error(w, "Failed to invalidate cache", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Enrichment cache invalidated (all=%t, group=%q, song=%q)", all, group, song)
+	w.WriteHeader(http.StatusNoContent)
+}
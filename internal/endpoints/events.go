@@ -0,0 +1,166 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// @Summary Отметить прослушивание песни
+// @Description Записывает событие прослушивания песни, используется для построения чартов
+// @Tags Events
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param X-User-Id header string false "ID пользователя"
+// @Success 201 {object} models.Play "Прослушивание записано"
+// @Failure 400 {string} string "Некорректный ID песни"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/RecordPlay [post]
+func RecordPlay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <RecordPlay> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	play := models.Play{SongID: song.ID, UserID: r.Header.Get(userIDHeader)}
+	if err := db.Create(&play).Error; err != nil {
+		log.Printf("Error: Failed to save play: %v", err)
+		http.Error(w, "Failed to save play", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(play)
+}
+
+// @Summary Добавить песню в избранное
+// @Description Отмечает песню как избранную для пользователя из заголовка X-User-Id
+// @Tags Events
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param X-User-Id header string true "ID пользователя"
+// @Success 201 {object} models.Favorite "Песня добавлена в избранное"
+// @Failure 400 {string} string "Некорректный ID песни или отсутствует пользователь"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 409 {string} string "Песня уже в избранном"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/AddFavorite [post]
+func AddFavorite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <AddFavorite> endpoint...")
+
+	userId := r.Header.Get(userIDHeader)
+	if userId == "" {
+		log.Println("Error: Missing X-User-Id header")
+		http.Error(w, "Missing X-User-Id header", http.StatusBadRequest)
+		return
+	}
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	favorite := models.Favorite{SongID: song.ID, UserID: userId}
+	if err := db.Create(&favorite).Error; err != nil {
+		if database.IsUniqueViolation(err) {
+			log.Printf("Error: Song %d is already in favorites of user %s", song.ID, userId)
+			http.Error(w, "Song is already in favorites", http.StatusConflict)
+			return
+		}
+		log.Printf("Error: Failed to save favorite: %v", err)
+		http.Error(w, "Failed to save favorite", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(favorite)
+}
+
+// @Summary Удалить песню из избранного
+// @Description Убирает песню из избранного пользователя из заголовка X-User-Id
+// @Tags Events
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param X-User-Id header string true "ID пользователя"
+// @Success 204 "Песня удалена из избранного"
+// @Failure 400 {string} string "Некорректный ID песни или отсутствует пользователь"
+// @Failure 404 {string} string "Песня не найдена в избранном"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Router /api/DeleteFavorite [delete]
+func DeleteFavorite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteFavorite> endpoint...")
+
+	userId := r.Header.Get(userIDHeader)
+	if userId == "" {
+		log.Println("Error: Missing X-User-Id header")
+		http.Error(w, "Missing X-User-Id header", http.StatusBadRequest)
+		return
+	}
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	result := db.Where("song_id = ? AND user_id = ?", songIntId, userId).Delete(&models.Favorite{})
+	if result.Error != nil {
+		log.Printf("Error: Failed to delete favorite: %v", result.Error)
+		http.Error(w, "Failed to delete favorite", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("Error: Song %d is not in favorites of %s", songIntId, userId)
+		http.Error(w, "Favorite not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
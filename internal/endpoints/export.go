@@ -0,0 +1,286 @@
+package endpoints
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportField is a column of the export, names are the JSON names of models.Song
+type exportField struct {
+	name  string
+	value func(s *models.Song) interface{}
+}
+
+// exportFields in default order
+var exportFields = []exportField{
+	{"id", func(s *models.Song) interface{} { return s.ID }},
+	{"group", func(s *models.Song) interface{} { return s.Group }},
+	{"song", func(s *models.Song) interface{} { return s.Song }},
+	{"genre", func(s *models.Song) interface{} { return s.Genre }},
+	{"album", func(s *models.Song) interface{} { return s.Album }},
+	{"releaseDate", func(s *models.Song) interface{} { return releasedate.Format(s.ReleaseDate, s.ReleaseDatePrecision) }},
+	{"releaseDatePrecision", func(s *models.Song) interface{} { return s.ReleaseDatePrecision }},
+	{"text", func(s *models.Song) interface{} { return s.Text }},
+	{"link", func(s *models.Song) interface{} { return s.Link }},
+	{"status", func(s *models.Song) interface{} { return s.Status }},
+	{"enrichedAt", func(s *models.Song) interface{} { return s.EnrichedAt }},
+	{"createdAt", func(s *models.Song) interface{} { return s.CreatedAt }},
+	{"updatedAt", func(s *models.Song) interface{} { return s.UpdatedAt }},
+}
+
+// csvValue renders a field value as CSV cell
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// selectExportFields keeps requested fields in the requested order, empty means all
+func selectExportFields(value string) ([]exportField, error) {
+	if strings.TrimSpace(value) == "" {
+		return exportFields, nil
+	}
+	var selected []exportField
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, f := range exportFields {
+			if strings.EqualFold(f.name, name) {
+				selected = append(selected, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// songWriter writes one export format
+type songWriter interface {
+	begin() error
+	write(s *models.Song) error
+	end() error
+}
+
+type csvSongWriter struct {
+	w      *csv.Writer
+	fields []exportField
+	record []string
+}
+
+func (c *csvSongWriter) begin() error {
+	header := make([]string, len(c.fields))
+	for i, f := range c.fields {
+		header[i] = f.name
+	}
+	c.record = make([]string, len(c.fields))
+	return c.w.Write(header)
+}
+
+func (c *csvSongWriter) write(s *models.Song) error {
+	for i, f := range c.fields {
+		c.record[i] = csvValue(f.value(s))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvSongWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonSongWriter writes NDJSON or, with array, a JSON array
+type jsonSongWriter struct {
+	w      io.Writer
+	fields []exportField
+	array  bool
+	count  int
+}
+
+func (j *jsonSongWriter) begin() error {
+	if j.array {
+		_, err := io.WriteString(j.w, "[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonSongWriter) write(s *models.Song) error {
+	var b strings.Builder
+	if j.array && j.count > 0 {
+		b.WriteString(",")
+	}
+	j.count++
+	b.WriteString("{")
+	for i, f := range j.fields {
+		value, err := json.Marshal(f.value(s))
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(strconv.Quote(f.name) + ":")
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonSongWriter) end() error {
+	if j.array {
+		_, err := io.WriteString(j.w, "]\n")
+		return err
+	}
+	return nil
+}
+
+// @Summary Выгрузить библиотеку
+// @Description Потоково выгружает все песни, подходящие под фильтры, в CSV, NDJSON или JSON-массив. Строки читаются из БД курсором, поэтому размер выгрузки не ограничен памятью сервера
+// @Tags Songs
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param format query string false "csv (по умолчанию), ndjson или json"
+// @Param fields query string false "Поля через запятую, например id,group,song,releaseDate (по умолчанию все)"
+// @Param gzip query bool false "Сжать ответ (Content-Encoding: gzip)"
+// @Param group query string false "Фильтр по названию группы"
+// @Param song query string false "Фильтр по названию песни"
+// @Param genre query string false "Фильтр по жанру"
+// @Param status query string false "Фильтр по статусу обогащения"
+// @Success 200 {string} string "Файл выгрузки"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/ExportSongs [get]
+func ExportSongs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ExportSongs> endpoint...")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentTypes := map[string]string{
+		"csv":    "text/csv; charset=utf-8",
+		"ndjson": "application/x-ndjson",
+		"json":   "application/json",
+	}
+	if _, ok := contentTypes[format]; !ok {
+		log.Printf("Error: Unknown export format %q", format)
+		http.Error(w, "Unknown format, use csv, ndjson or json", http.StatusBadRequest)
+		return
+	}
+
+	fields, err := selectExportFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		log.Printf("Error: Invalid fields parameter: %v", err)
+		http.Error(w, "Invalid fields parameter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	compress := false
+	if v := r.URL.Query().Get("gzip"); v != "" {
+		if compress, err = strconv.ParseBool(v); err != nil {
+			log.Printf("Error: Invalid gzip parameter: %v", err)
+			http.Error(w, "Invalid gzip parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	db := database.GetDB()
+	query := db.Model(&models.Song{})
+	if group := r.URL.Query().Get("group"); group != "" {
+		query = query.Where("group_name ILIKE ?", "%"+group+"%")
+	}
+	if song := r.URL.Query().Get("song"); song != "" {
+		query = query.Where("song ILIKE ?", "%"+song+"%")
+	}
+	if genre := r.URL.Query().Get("genre"); genre != "" {
+		query = query.Where("LOWER(genre) = LOWER(?)", genre)
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	rows, err := query.Order("id").Rows()
+	if err != nil {
+		log.Printf("Error: Failed to query songs: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"songs.%s\"", format))
+	var out io.Writer = w
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	buffered := bufio.NewWriter(out)
+	defer buffered.Flush()
+
+	var writer songWriter
+	if format == "csv" {
+		writer = &csvSongWriter{w: csv.NewWriter(buffered), fields: fields}
+	} else {
+		writer = &jsonSongWriter{w: buffered, fields: fields, array: format == "json"}
+	}
+
+	// status is sent with the first bytes, later errors can only cut the stream
+	count := 0
+	err = writer.begin()
+	for err == nil && rows.Next() {
+		var song models.Song
+		if err = db.ScanRows(rows, &song); err == nil {
+			err = writer.write(&song)
+			count++
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		log.Printf("Error: Export stopped after %d songs: %v", count, err)
+		return
+	}
+	if err := writer.end(); err != nil {
+		log.Printf("Error: Failed to finish export: %v", err)
+		return
+	}
+	log.Printf("Info: Exported %d songs as %s", count, format)
+}
@@ -0,0 +1,273 @@
+package endpoints
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"music_library_api/internal/database"
+	"music_library_api/internal/importer"
+	"music_library_api/internal/ingest"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const maxImportSize = 64 << 20 // 64 MB
+
+// csvAliases maps common CSV headers to song fields, the columns parameter overrides them
+var csvAliases = map[string]string{
+	"group": "group", "artist": "group", "band": "group", "performer": "group",
+	"song": "song", "title": "song", "name": "song", "track": "song",
+	"genre": "genre", "style": "genre",
+	"album": "album", "record": "album",
+	"releasedate": "releaseDate", "release_date": "releaseDate", "released": "releaseDate", "date": "releaseDate", "year": "releaseDate",
+	"text": "text", "lyrics": "text",
+	"link": "link", "url": "link",
+}
+
+// parseColumns reads "Artist:group,Title:song" into a header - field map
+func parseColumns(value string) (map[string]string, error) {
+	columns := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		header, field, ok := strings.Cut(item, ":")
+		field = strings.TrimSpace(field)
+		if !ok || !knownImportField(field) {
+			return nil, fmt.Errorf("invalid column mapping %q, expected Header:field with field one of group, song, genre, album, releaseDate, text, link", item)
+		}
+		columns[strings.ToLower(strings.TrimSpace(header))] = field
+	}
+	return columns, nil
+}
+
+func knownImportField(field string) bool {
+	switch field {
+	case "group", "song", "genre", "album", "releaseDate", "text", "link":
+		return true
+	}
+	return false
+}
+
+// importCSV feeds CSV rows to the importer, the first line is the header
+func importCSV(body io.Reader, columns map[string]string, im *importer.Importer) ([]string, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1 // short rows are reported per row
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	fields := make([]string, len(header))
+	var ignored []string
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := columns[name]; ok {
+			fields[i] = field
+		} else if field, ok := csvAliases[name]; ok && len(columns) == 0 {
+			fields[i] = field
+		} else {
+			ignored = append(ignored, header[i])
+		}
+	}
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return ignored, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				im.Fail(row, parseErr.Error())
+				continue
+			}
+			return ignored, err
+		}
+
+		var req addSongRequest
+		for i, value := range record {
+			if i >= len(fields) {
+				break
+			}
+			switch fields[i] {
+			case "group":
+				req.Group = value
+			case "song":
+				req.Song = value
+			case "genre":
+				req.Genre = value
+			case "album":
+				req.Album = value
+			case "releaseDate":
+				req.ReleaseDate = value
+			case "text":
+				req.Text = value
+			case "link":
+				req.Link = value
+			}
+		}
+		addImportRow(im, row, req)
+	}
+}
+
+// importNDJSON feeds JSON lines (fields of models.Song) to the importer, empty lines are skipped
+func importNDJSON(body io.Reader, im *importer.Importer) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTextLength*4+64*1024)
+
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row++
+		var req addSongRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			im.Fail(row, "invalid JSON: "+err.Error())
+			continue
+		}
+		addImportRow(im, row, req)
+	}
+	return scanner.Err()
+}
+
+func addImportRow(im *importer.Importer, row int, req addSongRequest) {
+	song, err := req.toSong(true)
+	if err != nil {
+		im.Fail(row, err.Error())
+		return
+	}
+	im.Add(row, song)
+}
+
+// @Summary Массовый импорт песен
+// @Description Импортирует песни из CSV (первая строка - заголовок) или NDJSON (по одному объекту models.Song в строке). Каждая строка проверяется как в AddSong?mode=manual, песни сохраняются пачками в транзакциях.
+// @Description Заголовки CSV group/artist, song/title, genre, album, releaseDate/year, text/lyrics, link/url распознаются автоматически, другие задаются параметром columns. В ответе - результат каждой строки: created, updated, merged, skipped или failed с причиной
+// @Tags Songs
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "csv или ndjson (по умолчанию по Content-Type)"
+// @Param columns query string false "Соответствие заголовков CSV полям, например Artist:group,Title:song,Year:releaseDate (остальные столбцы не используются)"
+// @Param enrich query bool false "Поставить задачи обогащения, данные файла сохраняются как в режиме manual"
+// @Param onDuplicate query string false "skip (по умолчанию), update, merge (заполнить только пустые поля) или error"
+// @Param dryRun query bool false "Проверить файл без сохранения"
+// @Param body body string true "Содержимое файла"
+// @Success 200 {object} map[string]interface{} "report - результат строк (importer.Report), ignoredColumns - неиспользованные столбцы CSV, error - если файл прочитан не полностью"
+// @Success 207 {object} map[string]interface{} "Импорт прерван ошибкой сервера: report - записанные строки, error - причина"
+// @Failure 400 {string} string "Некорректные параметры или формат файла"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 413 {string} string "Файл больше 64 МБ"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/ImportSongs [post]
+func ImportSongs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ImportSongs> endpoint...")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = "csv"
+		case "application/x-ndjson", "application/jsonl", "application/json":
+			format = "ndjson"
+		}
+	}
+	if format != "csv" && format != "ndjson" {
+		log.Printf("Error: Unknown import format %q", format)
+		http.Error(w, "Unknown format, use csv or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	columns, err := parseColumns(r.URL.Query().Get("columns"))
+	if err != nil {
+		log.Printf("Error: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := importer.Options{OnDuplicate: r.URL.Query().Get("onDuplicate")}
+	switch opts.OnDuplicate {
+	case "", ingest.OnDuplicateSkip, ingest.OnDuplicateUpdate, ingest.OnDuplicateError, importer.OnDuplicateMerge:
+	default:
+		log.Printf("Error: Unknown onDuplicate %q", opts.OnDuplicate)
+		http.Error(w, "Unknown onDuplicate, use skip, update, merge or error", http.StatusBadRequest)
+		return
+	}
+	if v := r.URL.Query().Get("enrich"); v != "" {
+		if opts.Enrich, err = strconv.ParseBool(v); err != nil {
+			log.Printf("Error: Invalid enrich parameter: %v", err)
+			http.Error(w, "Invalid enrich parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			log.Printf("Error: Invalid dryRun parameter: %v", err)
+			http.Error(w, "Invalid dryRun parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	im := importer.New(database.GetDB(), opts)
+
+	var ignored []string
+	var readErr error
+	if format == "csv" {
+		ignored, readErr = importCSV(body, columns, im)
+	} else {
+		readErr = importNDJSON(body, im)
+	}
+	// rows read before a broken part of the file are still written
+	report, err := im.Finish()
+	if err != nil && report.Total == 0 {
+		log.Printf("Error: Import failed: %v", err)
+		http.Error(w, "Import failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(readErr, &tooLarge) && report.Total == 0 {
+		log.Printf("Error: Import file is larger than %d bytes", maxImportSize)
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if readErr != nil && report.Total == 0 {
+		log.Printf("Error: Failed to read import file: %v", readErr)
+		http.Error(w, "Invalid file: "+readErr.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Info: Imported %d rows: created %d, updated %d, merged %d, skipped %d, failed %d (dry run %t)",
+		report.Total, report.Created, report.Updated, report.Merged, report.Skipped, report.Failed, report.DryRun)
+
+	response := map[string]interface{}{
+		"report":         report,
+		"ignoredColumns": ignored,
+	}
+	if readErr != nil {
+		response["error"] = "file was read partially: " + readErr.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		// batches written before the failure stay stored and are in the report, a retry with onDuplicate=skip goes on
+		log.Printf("Error: Import stopped after %d rows: %v", report.Total, err)
+		response["error"] = "import stopped, rows after the report were not written: " + err.Error()
+		w.WriteHeader(http.StatusMultiStatus)
+	}
+	json.NewEncoder(w).Encode(response)
+}
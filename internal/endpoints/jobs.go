@@ -0,0 +1,236 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/enrichment"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// @Summary Статус задачи обогащения
+// @Description Возвращает статус фоновой задачи получения информации о песне: queued, running, done, failed
+// @Tags Jobs
+// @Produce json
+// @Param jobId query int true "ID задачи"
+// @Success 200 {object} models.EnrichmentJob "Задача"
+// @Failure 400 {string} string "Некорректный ID задачи"
+// @Failure 404 {string} string "Задача не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Router /api/GetJob [get]
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetJob> endpoint...")
+
+	jobIntId, err := strconv.Atoi(r.URL.Query().Get("jobId"))
+	if err != nil {
+		log.Printf("Error: Invalid jobId parameter: %v", err)
+		http.Error(w, "Invalid jobId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var job models.EnrichmentJob
+	if err := db.First(&job, jobIntId).Error; err != nil {
+		log.Printf("Error: Job with id %d not found", jobIntId)
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// @Summary Источники данных песни
+// @Description Для полей releaseDate, text и link показывает, откуда взято текущее значение: client, edit или имя провайдера метаданных
+// @Tags Jobs
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Success 200 {array} models.FieldProvenance "Источники полей"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректный ID песни"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/GetSongProvenance [get]
+func GetSongProvenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetSongProvenance> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	if redirectMerged(w, r, db, songIntId) {
+		return
+	}
+	var provenance []models.FieldProvenance
+	if err := db.Where("song_id = ?", songIntId).Order("field").Find(&provenance).Error; err != nil {
+		log.Printf("Error: Failed to find provenance: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provenance)
+}
+
+// maxPreviewSongs bounds dry run, every song is one upstream call
+const maxPreviewSongs = 20
+
+// @Summary Повторно получить данные песен
+// @Description Ставит задачи повторного получения releaseDate, text и link от провайдера метаданных для одной песни (songId) или набора по фильтрам. Обновляются только поля, полученные ранее от провайдера; значения клиента, правок и импорта сохраняются, поля неизвестного происхождения следуют ENRICHMENT_PRECEDENCE. Пустые значения провайдера ничего не стирают, статус manual сохраняется.
+// @Description dryRun=true ничего не сохраняет и показывает, что изменится (не более 20 песен)
+// @Tags Jobs
+// @Produce json
+// @Param songId query int false "ID песни"
+// @Param group query string false "Фильтр по названию группы"
+// @Param song query string false "Фильтр по названию песни"
+// @Param status query string false "Фильтр по статусу: enriched, failed, manual"
+// @Param olderThan query string false "Только песни, обновленные раньше чем указанный срок назад, например 720h"
+// @Param dryRun query bool false "Показать изменения без сохранения"
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 200 {object} map[string]interface{} "Предпросмотр изменений (dryRun)"
+// @Success 202 {object} map[string]interface{} "Задачи поставлены"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 403 {string} string "Требуются права администратора"
+// @Failure 404 {string} string "Песни не найдены"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 500 {string} string "Ошибка сервера"
+// @Router /api/ReEnrichSongs [post]
+func ReEnrichSongs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ReEnrichSongs> endpoint...")
+
+	if !isAdmin(r) {
+		log.Println("Error: ReEnrichSongs called without admin token")
+		http.Error(w, "Admin rights required", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	db := database.GetDB()
+	query := db.Model(&models.Song{})
+
+	if songId := q.Get("songId"); songId != "" {
+		songIntId, err := strconv.Atoi(songId)
+		if err != nil {
+			log.Printf("Error: Invalid songId parameter: %v", err)
+			http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("id = ?", songIntId)
+	}
+	if group := q.Get("group"); group != "" {
+		query = query.Where("group_name ILIKE ?", "%"+group+"%")
+	}
+	if song := q.Get("song"); song != "" {
+		query = query.Where("song ILIKE ?", "%"+song+"%")
+	}
+	if status := q.Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if olderThan := q.Get("olderThan"); olderThan != "" {
+		age, err := time.ParseDuration(olderThan)
+		if err != nil || age <= 0 {
+			log.Printf("Error: Invalid olderThan parameter: %q", olderThan)
+			http.Error(w, "Invalid olderThan parameter, use duration like 720h", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("COALESCE(enriched_at, created_at) < ?", time.Now().Add(-age))
+	}
+	// pending songs are handled by their own jobs
+	query = query.Where("status <> ?", models.SongStatusPending)
+
+	dryRun, _ := strconv.ParseBool(q.Get("dryRun"))
+
+	if dryRun {
+		var songs []models.Song
+		if err := query.Order("id").Limit(maxPreviewSongs + 1).Find(&songs).Error; err != nil {
+			log.Printf("Error: Failed to find songs: %v", err)
+			http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(songs) == 0 {
+			log.Println("Error: No songs match re-enrichment filters")
+			http.Error(w, "No songs found", http.StatusNotFound)
+			return
+		}
+		if len(songs) > maxPreviewSongs {
+			log.Printf("Error: Too many songs for dry run")
+			http.Error(w, fmt.Sprintf("Too many songs for dry run, narrow filters to %d songs", maxPreviewSongs), http.StatusBadRequest)
+			return
+		}
+
+		type preview struct {
+			SongID  int                      `json:"songId"`
+			Changes map[string]ingest.Change `json:"changes,omitempty"`
+			Error   string                   `json:"error,omitempty"`
+		}
+		previews := make([]preview, 0, len(songs))
+		for _, song := range songs {
+			changes, err := ingest.Preview(r.Context(), db, enrichment.GetProvider(), song)
+			p := preview{SongID: song.ID, Changes: changes}
+			if err != nil {
+				p.Error = err.Error()
+			}
+			previews = append(previews, p)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"dryRun": true, "songs": previews})
+		return
+	}
+
+	var ids []int
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		log.Printf("Error: Failed to find songs: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(ids) == 0 {
+		log.Println("Error: No songs match re-enrichment filters")
+		http.Error(w, "No songs found", http.StatusNotFound)
+		return
+	}
+
+	jobs, err := ingest.EnqueueRefresh(db, ids)
+	if err != nil {
+		log.Printf("Error: Failed to queue re-enrichment: %v", err)
+		http.Error(w, "Failed to queue re-enrichment", http.StatusInternalServerError)
+		return
+	}
+	jobIds := make([]int, len(jobs))
+	for i, job := range jobs {
+		jobIds[i] = job.ID
+	}
+
+	log.Printf("Info: Re-enrichment queued for %d of %d songs", len(jobs), len(ids))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"matched": len(ids),
+		"queued":  len(jobs),
+		"jobIds":  jobIds,
+	})
+}
@@ -0,0 +1,210 @@
+package endpoints
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"music_library_api/internal/database"
+	"music_library_api/internal/importer"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/library"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const maxLibrarySize = 256 << 20 // 256 MB, Library.xml of a large collection
+
+// importedPlaylist is a playlist of the library in the response
+type importedPlaylist struct {
+	Name       string `json:"name"`
+	PlaylistID int    `json:"playlistId,omitempty"` // 0 in dry run
+	Tracks     int    `json:"tracks"`
+	Matched    int    `json:"matched"` // tracks stored as songs
+}
+
+// openLibraryFiles returns the library and optional Rhythmbox playlists.xml from
+// a multipart form (fields library and playlists) or the raw body
+func openLibraryFiles(r *http.Request) (io.Reader, io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil, nil
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, nil, err
+	}
+	lib, _, err := r.FormFile("library")
+	if err != nil {
+		return nil, nil, fmt.Errorf("no library file in the form: %w", err)
+	}
+	playlists, _, err := r.FormFile("playlists")
+	if errors.Is(err, http.ErrMissingFile) {
+		return lib, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return lib, playlists, nil
+}
+
+// trackRequest maps a library track to song fields, the most precise known date is used
+func trackRequest(t library.Track) addSongRequest {
+	req := addSongRequest{Group: t.Artist, Song: t.Name, Genre: t.Genre, Album: t.Album}
+	if !t.ReleaseDate.IsZero() {
+		req.ReleaseDate = t.ReleaseDate.Format("2006-01-02")
+	} else if t.Year > 0 {
+		req.ReleaseDate = strconv.Itoa(t.Year)
+	}
+	return req
+}
+
+// @Summary Импорт медиатеки
+// @Description Импортирует песни и плейлисты из медиатеки iTunes / Apple Music (Library.xml) или Rhythmbox (rhythmdb.xml и необязательный playlists.xml). Исполнитель становится группой, название - песней, год или дата выпуска - releaseDate.
+// @Description Файл передается телом запроса или формой multipart (поля library и playlists). Подкасты, видео и радио пропускаются. В ответе - результат каждого трека: created, merged, updated, skipped или failed, и созданные плейлисты
+// @Tags Playlists
+// @Accept xml
+// @Accept mpfd
+// @Produce json
+// @Param X-User-Id header string false "Владелец плейлистов"
+// @Param format query string false "itunes или rhythmbox (по умолчанию по содержимому)"
+// @Param onDuplicate query string false "merge (по умолчанию, заполнить только пустые поля), skip, update или error"
+// @Param enrich query bool false "Поставить задачи обогащения новых песен"
+// @Param playlists query bool false "Импортировать плейлисты (по умолчанию true)"
+// @Param dryRun query bool false "Проверить файл без сохранения"
+// @Param body body string false "Содержимое Library.xml или rhythmdb.xml"
+// @Success 200 {object} map[string]interface{} "source, report - результат треков (importer.Report), playlists - плейлисты медиатеки, error - если плейлисты не сохранены"
+// @Success 207 {object} map[string]interface{} "Импорт прерван ошибкой сервера: report - записанные треки, error - причина, плейлисты не созданы"
+// @Failure 400 {string} string "Некорректные параметры или файл"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 413 {string} string "Файл больше 256 МБ"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/ImportLibrary [post]
+func ImportLibrary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ImportLibrary> endpoint...")
+
+	q := r.URL.Query()
+	opts := importer.Options{OnDuplicate: q.Get("onDuplicate")}
+	switch opts.OnDuplicate {
+	case "":
+		opts.OnDuplicate = importer.OnDuplicateMerge
+	case ingest.OnDuplicateSkip, ingest.OnDuplicateUpdate, ingest.OnDuplicateError, importer.OnDuplicateMerge:
+	default:
+		log.Printf("Error: Unknown onDuplicate %q", opts.OnDuplicate)
+		http.Error(w, "Unknown onDuplicate, use merge, skip, update or error", http.StatusBadRequest)
+		return
+	}
+	var err error
+	withPlaylists := true
+	for name, value := range map[string]*bool{"enrich": &opts.Enrich, "dryRun": &opts.DryRun, "playlists": &withPlaylists} {
+		if v := q.Get(name); v != "" {
+			if *value, err = strconv.ParseBool(v); err != nil {
+				log.Printf("Error: Invalid %s parameter: %v", name, err)
+				http.Error(w, "Invalid "+name+" parameter", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxLibrarySize)
+	libFile, playlistsFile, err := openLibraryFiles(r)
+	var lib *library.Library
+	if err == nil {
+		reader := bufio.NewReader(libFile)
+		source := strings.ToLower(q.Get("format"))
+		if source == "" {
+			head, _ := reader.Peek(4096)
+			source, err = library.Detect(head)
+		}
+		if err == nil {
+			lib, err = library.Parse(source, reader, playlistsFile)
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Error: Library file is larger than %d bytes", maxLibrarySize)
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error: Invalid library file: %v", err)
+		http.Error(w, "Invalid library file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	im := importer.New(db, opts)
+	rows := make(map[string]int, len(lib.Tracks)) // track ID - row
+	for i, track := range lib.Tracks {
+		row := i + 1
+		rows[track.ID] = row
+		if track.Skip != "" {
+			im.Skip(row, track.Skip)
+			continue
+		}
+		addImportRow(im, row, trackRequest(track))
+	}
+	report, importErr := im.Finish()
+	if importErr != nil && report.Total == 0 {
+		log.Printf("Error: Library import failed: %v", importErr)
+		http.Error(w, "Import failed: "+importErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Imported %s library of %d tracks: created %d, merged %d, updated %d, skipped %d, failed %d (dry run %t)",
+		lib.Source, report.Total, report.Created, report.Merged, report.Updated, report.Skipped, report.Failed, report.DryRun)
+
+	playlists := []importedPlaylist{}
+	var playlistsErr error
+	// playlists of a partly stored library would miss tracks, a retry creates them
+	if withPlaylists && importErr == nil {
+		playlistsErr = db.Transaction(func(tx *gorm.DB) error {
+			for _, lp := range lib.Playlists {
+				ids := make([]int, 0, len(lp.TrackIDs))
+				for _, trackId := range lp.TrackIDs {
+					if id := im.SongIDs[rows[trackId]]; id != 0 {
+						ids = append(ids, id)
+					}
+				}
+				imported := importedPlaylist{Name: lp.Name, Tracks: len(lp.TrackIDs), Matched: len(ids)}
+				if !opts.DryRun {
+					p, err := createPlaylist(tx, lp.Name, r.Header.Get(userIDHeader), ids)
+					if err != nil {
+						return err
+					}
+					imported.PlaylistID = p.ID
+				}
+				playlists = append(playlists, imported)
+			}
+			return nil
+		})
+	}
+
+	response := map[string]interface{}{
+		"source":    lib.Source,
+		"report":    report,
+		"playlists": playlists,
+	}
+	if playlistsErr != nil {
+		// songs are already stored, a retry merges them and creates the playlists
+		log.Printf("Error: Failed to save library playlists: %v", playlistsErr)
+		response["playlists"] = []importedPlaylist{}
+		response["error"] = "playlists were not saved: " + playlistsErr.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if importErr != nil {
+		log.Printf("Error: Library import stopped after %d tracks: %v", report.Total, importErr)
+		response["error"] = "import stopped, tracks after the report and playlists were not saved: " + importErr.Error()
+		w.WriteHeader(http.StatusMultiStatus)
+	}
+	json.NewEncoder(w).Encode(response)
+}
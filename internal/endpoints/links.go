@@ -0,0 +1,373 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/links"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// addLinkRequest body of AddSongLink
+type addLinkRequest struct {
+	URL      string `json:"url"`
+	Platform string `json:"platform"` // optional, checked against the URL
+}
+
+// attachLinks fills Links of songs
+func attachLinks(db *gorm.DB, songs []models.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	ids := make([]int, len(songs))
+	for i, s := range songs {
+		ids[i] = s.ID
+	}
+	var rows []models.SongLink
+	if err := db.Where("song_id IN ?", ids).Order("id").Find(&rows).Error; err != nil {
+		return err
+	}
+	bySong := map[int][]models.SongLink{}
+	for _, l := range rows {
+		bySong[l.SongID] = append(bySong[l.SongID], l)
+	}
+	for i := range songs {
+		songs[i].Links = bySong[songs[i].ID]
+	}
+	return nil
+}
+
+// syncLegacyLink keeps the legacy link field of old clients pointing to a link of the song:
+// when it is empty or was the removed link, the first YouTube link or else the first link takes its place
+func syncLegacyLink(tx *gorm.DB, songId int, removed string) (string, error) {
+	var song models.Song
+	if err := tx.Select("id", "link").First(&song, songId).Error; err != nil {
+		return "", err
+	}
+	if song.Link != "" && song.Link != removed {
+		return song.Link, nil
+	}
+	var rows []models.SongLink
+	if err := tx.Where("song_id = ?", songId).Order("id").Find(&rows).Error; err != nil {
+		return "", err
+	}
+	primary := ""
+	for _, l := range rows {
+		if l.Platform == links.PlatformYouTube {
+			primary = l.URL
+			break
+		}
+	}
+	if primary == "" && len(rows) > 0 {
+		primary = rows[0].URL
+	}
+	if primary != song.Link {
+		// without hooks, the link is in the collection already
+		if err := tx.Model(&models.Song{}).Where("id = ?", songId).UpdateColumn("link", primary).Error; err != nil {
+			return "", err
+		}
+	}
+	return primary, nil
+}
+
+// @Summary Получить ссылки песни
+// @Description Возвращает внешние ссылки песни по платформам (youtube, spotify, applemusic, bandcamp, soundcloud, other) с каноническим адресом, ID на платформе и адресом плеера для встраивания, status, httpStatus, failureStreak и checkedAt - результат последней фоновой проверки. Поле link - прежняя одиночная ссылка для старых клиентов
+// @Tags Links
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param platform query string false "Только ссылки этой платформы"
+// @Success 200 {object} map[string]interface{} "songId, link, links - список models.SongLink"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректные параметры"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера"
+// @Router /api/GetSongLinks [get]
+func GetSongLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetSongLinks> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	platform := r.URL.Query().Get("platform")
+	if platform != "" && !links.KnownPlatform(platform) {
+		log.Printf("Error: Unknown platform %q", platform)
+		http.Error(w, "Unknown platform", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		if redirectMerged(w, r, db, songIntId) {
+			return
+		}
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	query := db.Where("song_id = ?", song.ID)
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	rows := []models.SongLink{}
+	if err := query.Order("id").Find(&rows).Error; err != nil {
+		log.Printf("Error: Failed to get links of song %d: %v", song.ID, err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"songId": song.ID,
+		"link":   song.Link,
+		"links":  rows,
+	})
+}
+
+// @Summary Добавить ссылку песни
+// @Description Проверяет ссылку по правилам платформы и сохраняет ее канонический адрес: для YouTube - watch?v=ID из youtu.be, shorts и embed, для Spotify - open.spotify.com из spotify: URI, для Apple Music - music.apple.com. Платформа определяется по адресу, переданная платформа должна с ним совпадать (other - любая ссылка http(s))
+// @Description Если у песни нет прежней ссылки link, ею становится первая ссылка YouTube или первая добавленная
+// @Tags Links
+// @Accept json
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param body body addLinkRequest true "Ссылка и необязательная платформа"
+// @Success 201 {object} map[string]interface{} "link - сохраненная models.SongLink, legacyLink - поле link песни"
+// @Failure 400 {string} string "Некорректная ссылка или платформа"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 409 {object} map[string]interface{} "Ссылка уже есть у песни"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/AddSongLink [post]
+func AddSongLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <AddSongLink> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	var req addLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	link, err := links.Parse(req.URL, req.Platform)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	row := models.NewSongLink(song.ID, link)
+	var legacy string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrDuplicatedKey
+		}
+		legacy, err = syncLegacyLink(tx, song.ID, "")
+		return err
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		var existing models.SongLink
+		db.Where("song_id = ? AND url = ?", song.ID, link.URL).First(&existing)
+		log.Printf("Info: Song %d already has link %s", song.ID, link.URL)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "song already has this link",
+			"link":  existing,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Error: Failed to save link of song %d: %v", song.ID, err)
+		http.Error(w, "Error saving to database: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: %s link %d added to song %d", link.Platform, row.ID, song.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"link":       row,
+		"legacyLink": legacy,
+	})
+}
+
+// @Summary Удалить ссылку песни
+// @Description Удаляет ссылку (linkId). Если это была прежняя ссылка link песни, ее место занимает первая оставшаяся ссылка YouTube или первая оставшаяся ссылка
+// @Tags Links
+// @Produce json
+// @Param linkId query int true "ID ссылки"
+// @Success 200 {object} map[string]interface{} "deleted: true, legacyLink - поле link песни"
+// @Failure 400 {string} string "Некорректный ID ссылки"
+// @Failure 404 {string} string "Ссылка не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Failure 500 {string} string "Ошибка сервера при удалении"
+// @Router /api/DeleteSongLink [delete]
+func DeleteSongLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteSongLink> endpoint...")
+
+	linkIntId, err := strconv.Atoi(r.URL.Query().Get("linkId"))
+	if err != nil {
+		log.Printf("Error: Invalid linkId parameter: %v", err)
+		http.Error(w, "Invalid linkId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var row models.SongLink
+	if err := db.First(&row, linkIntId).Error; err != nil {
+		log.Printf("Error: Link with id %d not found", linkIntId)
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	var legacy string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&row).Error; err != nil {
+			return err
+		}
+		legacy, err = syncLegacyLink(tx, row.SongID, row.URL)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error: Failed to delete link %d: %v", row.ID, err)
+		http.Error(w, "Failed to delete link", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Link %d of song %d deleted", row.ID, row.SongID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted":    true,
+		"legacyLink": legacy,
+	})
+}
+
+// @Summary Неработающие ссылки
+// @Description Отчет фоновой проверки ссылок (LINKCHECK_INTERVAL): ссылки, последняя проверка которых не удалась, с кодом ответа или ошибкой и числом неудачных проверок подряд. Сначала ссылки с самой длинной серией неудач
+// @Tags Links
+// @Produce json
+// @Param minStreak query int false "Минимум неудачных проверок подряд (по умолчанию 1)"
+// @Param platform query string false "Только ссылки этой платформы"
+// @Param page query int false "Номер страницы (по умолчанию 1)"
+// @Param limit query int false "Количество ссылок на странице (по умолчанию 20)"
+// @Success 200 {object} map[string]interface{} "links - ссылки (models.SongLink) с group и song песни, page, perPage, total"
+// @Failure 400 {string} string "Некорректные параметры"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка чтения из БД"
+// @Router /api/GetBrokenLinks [get]
+func GetBrokenLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetBrokenLinks> endpoint...")
+
+	q := r.URL.Query()
+	minStreak := 1
+	if v := q.Get("minStreak"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Printf("Error: Invalid minStreak parameter %q", v)
+			http.Error(w, "Invalid minStreak parameter, expected positive integer", http.StatusBadRequest)
+			return
+		}
+		minStreak = n
+	}
+	platform := q.Get("platform")
+	if platform != "" && !links.KnownPlatform(platform) {
+		log.Printf("Error: Unknown platform %q", platform)
+		http.Error(w, "Unknown platform", http.StatusBadRequest)
+		return
+	}
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	db := database.GetDB()
+	query := db.Model(&models.SongLink{}).
+		Joins("JOIN songs ON songs.id = song_links.song_id").
+		Where("song_links.status = ? AND song_links.failure_streak >= ?", models.LinkStatusBroken, minStreak)
+	if platform != "" {
+		query = query.Where("song_links.platform = ?", platform)
+	}
+	query = query.Session(&gorm.Session{}) // reused for count and page
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("Error: Failed to count broken links: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type brokenLink struct {
+		models.SongLink
+		Group string `json:"group" gorm:"column:group_name"`
+		Song  string `json:"song" gorm:"column:song"`
+	}
+	result := []brokenLink{}
+	err = query.Select("song_links.*, songs.group_name, songs.song").
+		Order("song_links.failure_streak DESC, song_links.id").
+		Offset((page - 1) * limit).Limit(limit).Scan(&result).Error
+	if err != nil {
+		log.Printf("Error: Failed to load broken links: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"links":   result,
+		"page":    page,
+		"perPage": limit,
+		"total":   total,
+	})
+}
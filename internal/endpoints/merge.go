@@ -0,0 +1,344 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fields of a song that can be taken from the duplicate
+var mergeFields = []string{"group", "song", "genre", "album", ingest.FieldReleaseDate, ingest.FieldText, ingest.FieldLink}
+
+// mergeRequest body of MergeSongs, fields maps a field to its winner: "song" or "duplicate"
+type mergeRequest struct {
+	Fields map[string]string `json:"fields" example:"text:duplicate"`
+}
+
+// mergeResult is what MergeSongs did
+type mergeResult struct {
+	Song      models.Song    `json:"song"`
+	MergedID  int            `json:"mergedId"`
+	Winners   map[string]int `json:"winners"` // field - ID of the song whose value was kept
+	Moved     map[string]int `json:"moved"`   // table - rows moved to the song
+	Discarded map[string]int `json:"discarded"`
+
+	audioKeys []string // blobs of discarded audio, released after the commit
+	imageIds  []int    // images of discarded covers, released after the commit
+}
+
+// errMergeNotFound is returned when one of the songs does not exist
+var errMergeNotFound = errors.New("song not found")
+
+// redirectMerged answers 301 to the same URL with the new songId if the song was merged into another one
+func redirectMerged(w http.ResponseWriter, r *http.Request, db *gorm.DB, songId int) bool {
+	var redirect models.SongRedirect
+	if err := db.First(&redirect, "from_id = ?", songId).Error; err != nil {
+		return false
+	}
+	query := r.URL.Query()
+	query.Set("songId", strconv.Itoa(redirect.ToID))
+	target := *r.URL
+	target.RawQuery = query.Encode()
+
+	log.Printf("Info: Song %d was merged into song %d, redirecting", songId, redirect.ToID)
+	http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	return true
+}
+
+// mergeSongs moves everything of the duplicate to the song and deletes the duplicate
+func mergeSongs(tx *gorm.DB, songId, duplicateId int, winners map[string]string) (*mergeResult, error) {
+	var song, duplicate models.Song
+	locking := clause.Locking{Strength: "UPDATE"}
+	if err := tx.Clauses(locking).First(&song, songId).Error; err != nil {
+		return nil, fmt.Errorf("song %d: %w", songId, errMergeNotFound)
+	}
+	if err := tx.Clauses(locking).First(&duplicate, duplicateId).Error; err != nil {
+		return nil, fmt.Errorf("song %d: %w", duplicateId, errMergeNotFound)
+	}
+
+	result := &mergeResult{
+		MergedID:  duplicateId,
+		Winners:   map[string]int{},
+		Moved:     map[string]int{},
+		Discarded: map[string]int{},
+	}
+
+	// field values, empty value of the song is filled from the duplicate unless told otherwise
+	takeDuplicate := func(field string, songEmpty, duplicateEmpty bool) bool {
+		winner, ok := winners[field]
+		take := winner == "duplicate" || (!ok && songEmpty && !duplicateEmpty)
+		if take {
+			result.Winners[field] = duplicateId
+		} else {
+			result.Winners[field] = songId
+		}
+		return take
+	}
+	var fromDuplicate []string
+	if takeDuplicate("group", song.Group == "", duplicate.Group == "") {
+		song.Group = duplicate.Group
+	}
+	if takeDuplicate("song", song.Song == "", duplicate.Song == "") {
+		song.Song = duplicate.Song
+	}
+	if takeDuplicate("genre", song.Genre == "", duplicate.Genre == "") {
+		song.Genre = duplicate.Genre
+	}
+	if takeDuplicate("album", song.Album == "", duplicate.Album == "") {
+		song.Album = duplicate.Album
+	}
+	if takeDuplicate(ingest.FieldReleaseDate, song.ReleaseDate.IsZero(), duplicate.ReleaseDate.IsZero()) {
+		song.ReleaseDate, song.ReleaseDatePrecision = duplicate.ReleaseDate, duplicate.ReleaseDatePrecision
+		fromDuplicate = append(fromDuplicate, ingest.FieldReleaseDate)
+	}
+	textFromDuplicate := takeDuplicate(ingest.FieldText, song.Text == "", duplicate.Text == "")
+	if textFromDuplicate {
+		song.Text = duplicate.Text
+		fromDuplicate = append(fromDuplicate, ingest.FieldText)
+	}
+	if takeDuplicate(ingest.FieldLink, song.Link == "", duplicate.Link == "") {
+		song.Link = duplicate.Link
+		fromDuplicate = append(fromDuplicate, ingest.FieldLink)
+	}
+
+	// provenance of values taken from the duplicate goes with them
+	if len(fromDuplicate) > 0 {
+		if err := tx.Where("song_id = ? AND field IN ?", songId, fromDuplicate).Delete(&models.FieldProvenance{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&models.FieldProvenance{}).Where("song_id = ? AND field IN ?", duplicateId, fromDuplicate).
+			Update("song_id", songId).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("song_id = ?", duplicateId).Delete(&models.FieldProvenance{}).Error; err != nil {
+		return nil, err
+	}
+
+	// rows without per-song uniqueness are simply moved
+	move := func(name string, model interface{}) error {
+		res := tx.Model(model).Where("song_id = ?", duplicateId).Update("song_id", songId)
+		result.Moved[name] = int(res.RowsAffected)
+		return res.Error
+	}
+	// rows of the losing song that clash by key with the rows of the kept one
+	clashing := func(model interface{}, key string, preferDuplicate bool) *gorm.DB {
+		keep, drop := songId, duplicateId
+		if preferDuplicate {
+			keep, drop = duplicateId, songId
+		}
+		clash := tx.Model(model).Select(key).Where("song_id = ?", keep)
+		return tx.Model(model).Where("song_id = ? AND "+key+" IN (?)", drop, clash)
+	}
+	// rows unique per song and key: the kept song wins, unless preferDuplicate
+	moveUnique := func(name string, model interface{}, key string, preferDuplicate bool) error {
+		res := clashing(model, key, preferDuplicate).Delete(model)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Discarded[name] = int(res.RowsAffected)
+		return move(name, model)
+	}
+
+	steps := []func() error{
+		func() error { return move("plays", &models.Play{}) },
+		func() error { return move("playlistEntries", &models.PlaylistEntry{}) },
+		func() error { return moveUnique("favorites", &models.Favorite{}, "user_id", false) },
+		func() error { return moveUnique("reviews", &models.Review{}, "user_id", false) },
+		func() error { return move("annotations", &models.Annotation{}) },
+		// synced lyrics and translations belong to the text, they follow its winner;
+		// synced lyrics are one per song, so the constant key clashes with any row
+		func() error { return moveUnique("syncedLyrics", &models.SyncedLyrics{}, "1", textFromDuplicate) },
+		func() error { return moveUnique("translations", &models.Translation{}, "lang", textFromDuplicate) },
+		// one audio file and cover per song
+		func() error {
+			if err := clashing(&models.AudioAsset{}, "1", false).Pluck("key", &result.audioKeys).Error; err != nil {
+				return err
+			}
+			return moveUnique("audio", &models.AudioAsset{}, "1", false)
+		},
+		func() error {
+			if err := clashing(&models.SongCover{}, "1", false).Pluck("image_id", &result.imageIds).Error; err != nil {
+				return err
+			}
+			return moveUnique("cover", &models.SongCover{}, "1", false)
+		},
+		func() error { return moveUnique("links", &models.SongLink{}, "url", false) },
+		func() error {
+			moved, discarded, err := moveRelations(tx, songId, duplicateId)
+			result.Moved["relations"], result.Discarded["relations"] = moved, discarded
+			return err
+		},
+		func() error { return move("jobs", &models.EnrichmentJob{}) },
+		// charts are rebuilt by the charts worker
+		func() error {
+			return tx.Where("song_id = ?", duplicateId).Delete(&models.ChartEntry{}).Error
+		},
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	// old IDs of the duplicate and the duplicate itself now lead to the song
+	if err := tx.Model(&models.SongRedirect{}).Where("to_id = ?", duplicateId).Update("to_id", songId).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&models.SongRedirect{FromID: duplicateId, ToID: songId}).Error; err != nil {
+		return nil, err
+	}
+
+	// the duplicate goes first, it may hold the key of the merged song
+	if err := tx.Delete(&models.Song{}, duplicateId).Error; err != nil {
+		return nil, err
+	}
+	key := dedup.Key(song.Group, song.Song)
+	var taken int64
+	if err := tx.Model(&models.Song{}).Where("dedup_key = ? AND id <> ?", key, songId).Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken == 0 {
+		song.DedupKey = &key
+	} else {
+		song.DedupKey = nil // still a duplicate of another song
+	}
+	song.UpdatedAt = time.Now()
+	if err := tx.Save(&song).Error; err != nil {
+		return nil, err
+	}
+
+	// annotations of both songs are anchored to the kept text
+	if err := ingest.ReanchorAnnotations(tx, song); err != nil {
+		return nil, err
+	}
+
+	result.Song = song
+	return result, nil
+}
+
+// @Summary Объединить дубликаты
+// @Description Переносит песню duplicateId в песню songId: для каждого поля (group, song, genre, releaseDate, text, link) можно выбрать, чье значение остается ("song" или "duplicate", по умолчанию song, пустые поля заполняются из дубликата).
+// @Description Прослушивания, записи плейлистов, избранное, отзывы, аннотации, синхронизированный текст, переводы, аудиофайл, обложка, ссылки, связи с другими песнями, задачи и источники полей переносятся, дубликат удаляется, а запросы по его ID перенаправляются (301). Все изменения выполняются в одной транзакции. Только для администратора
+// @Tags Songs
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Param songId query int true "ID песни, которая остается"
+// @Param duplicateId query int true "ID дубликата, который удаляется"
+// @Param body body mergeRequest false "Выбор значений полей"
+// @Success 200 {object} mergeResult "Песни объединены"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 403 {string} string "Требуются права администратора"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 500 {string} string "Ошибка сервера при объединении"
+// @Router /api/MergeSongs [post]
+func MergeSongs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <MergeSongs> endpoint...")
+
+	if !isAdmin(r) {
+		log.Println("Error: MergeSongs called without admin token")
+		http.Error(w, "Admin rights required", http.StatusForbidden)
+		return
+	}
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	duplicateIntId, err := strconv.Atoi(r.URL.Query().Get("duplicateId"))
+	if err != nil {
+		log.Printf("Error: Invalid duplicateId parameter: %v", err)
+		http.Error(w, "Invalid duplicateId parameter", http.StatusBadRequest)
+		return
+	}
+	if songIntId == duplicateIntId {
+		log.Printf("Error: Song %d can not be merged into itself", songIntId)
+		http.Error(w, "songId and duplicateId must differ", http.StatusBadRequest)
+		return
+	}
+
+	var req mergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	for field, winner := range req.Fields {
+		known := false
+		for _, f := range mergeFields {
+			known = known || f == field
+		}
+		if !known || (winner != "song" && winner != "duplicate") {
+			log.Printf("Error: Invalid field choice %s=%s", field, winner)
+			http.Error(w, fmt.Sprintf("Invalid choice for field %q, use song or duplicate", field), http.StatusBadRequest)
+			return
+		}
+	}
+
+	db := database.GetDB()
+	var result *mergeResult
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = mergeSongs(tx, songIntId, duplicateIntId, req.Fields)
+		return err
+	})
+	if errors.Is(err, errMergeNotFound) {
+		log.Printf("Error: Failed to merge: %v", err)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error: Failed to merge song %d into %d: %v", duplicateIntId, songIntId, err)
+		http.Error(w, "Failed to merge songs", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Song %d merged into song %d", duplicateIntId, songIntId)
+
+	// the discarded rows are gone, a failed blob delete only leaves an unused file
+	for _, key := range result.audioKeys {
+		if err := releaseBlob(r.Context(), db, &models.AudioAsset{}, key); err != nil {
+			log.Printf("Error: Failed to delete audio file %s: %v", key, err)
+		}
+	}
+	for _, imageId := range result.imageIds {
+		if err := releaseImage(r.Context(), db, imageId); err != nil {
+			log.Printf("Error: Failed to delete image %d: %v", imageId, err)
+		}
+	}
+
+	songs := []models.Song{result.Song}
+	if err := attachRatings(db, songs); err != nil {
+		log.Printf("Error: Failed to get rating of song %d: %v", songIntId, err)
+	}
+	if err := attachCovers(db, songs); err != nil {
+		log.Printf("Error: Failed to get cover of song %d: %v", songIntId, err)
+	}
+	if err := attachLinks(db, songs); err != nil {
+		log.Printf("Error: Failed to get links of song %d: %v", songIntId, err)
+	}
+	result.Song = songs[0]
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,436 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/links"
+	"music_library_api/internal/models"
+	"music_library_api/internal/playlist"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const maxPlaylistSize = 8 << 20 // 8 MB
+
+// unmatchedEntry is a playlist entry without a song in the library
+type unmatchedEntry struct {
+	Position int `json:"position"`
+	playlist.Entry
+	Reason string `json:"reason"`
+}
+
+// matchableLink is the canonical form of a web location, other locations are compared as is
+func matchableLink(location string) string {
+	if link, err := links.Parse(location, ""); err == nil {
+		return link.URL
+	}
+	return location
+}
+
+// matchEntries finds songs of playlist entries by artist and title, then by any of their links.
+// An entry with title only matches when exactly one song has that title.
+// Returns song ID per entry, 0 for unmatched ones.
+func matchEntries(db *gorm.DB, entries []playlist.Entry) ([]int, []unmatchedEntry, error) {
+	ids := make([]int, len(entries))
+
+	var keys, urls, titles []string
+	for _, e := range entries {
+		if e.Artist != "" {
+			keys = append(keys, dedup.Key(e.Artist, e.Title))
+		} else if e.Title != "" {
+			titles = append(titles, e.Title)
+		}
+		if e.Location != "" {
+			urls = append(urls, matchableLink(e.Location))
+		}
+	}
+	byKey := map[string]int{}
+	byLink := map[string]int{}
+	if len(keys) > 0 {
+		var songs []models.Song
+		if err := db.Select("id", "dedup_key").Where("dedup_key IN ?", keys).Find(&songs).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, s := range songs {
+			byKey[*s.DedupKey] = s.ID
+		}
+	}
+	if len(urls) > 0 {
+		var rows []models.SongLink
+		if err := db.Select("song_id", "url").Where("url IN ?", urls).Order("song_id").Find(&rows).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, l := range rows {
+			if _, ok := byLink[l.URL]; !ok {
+				byLink[l.URL] = l.SongID
+			}
+		}
+	}
+	byTitle, err := matchTitles(db, titles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unmatched []unmatchedEntry
+	for i, e := range entries {
+		if e.Artist != "" {
+			ids[i] = byKey[dedup.Key(e.Artist, e.Title)]
+		}
+		if ids[i] == 0 && e.Location != "" {
+			ids[i] = byLink[matchableLink(e.Location)]
+		}
+		if ids[i] != 0 {
+			continue
+		}
+
+		reason := "no song with this artist and title"
+		if e.Artist == "" && e.Title != "" {
+			match := byTitle[dedup.Name(e.Title)]
+			switch {
+			case match.count == 1:
+				ids[i] = match.id
+				continue
+			case match.count > 1:
+				reason = fmt.Sprintf("%d songs with this title, artist is unknown", match.count)
+			default:
+				reason = "no song with this title"
+			}
+		}
+		if e.Title == "" {
+			reason = "entry has no title"
+		}
+		unmatched = append(unmatched, unmatchedEntry{Position: i, Entry: e, Reason: reason})
+	}
+	return ids, unmatched, nil
+}
+
+// titleMatch is a song with the normalized title and the number of such songs
+type titleMatch struct {
+	id, count int
+}
+
+// matchTitles finds songs by normalized titles in one query. The title half of dedup_key
+// is normalized by dedup.Name too, so accents, case and punctuation do not matter
+func matchTitles(db *gorm.DB, titles []string) (map[string]titleMatch, error) {
+	matches := map[string]titleMatch{}
+	var names []string
+	for _, title := range titles {
+		if name := dedup.Name(title); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return matches, nil
+	}
+	var songs []models.Song
+	// dedup.Name keeps only letters, digits and spaces, the first "|" ends the artist half
+	if err := db.Select("id", "dedup_key").Where("split_part(dedup_key, '|', 2) IN ?", names).Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range songs {
+		_, name, _ := strings.Cut(*s.DedupKey, "|")
+		m := matches[name]
+		m.id = s.ID
+		m.count++
+		matches[name] = m
+	}
+	return matches, nil
+}
+
+// createPlaylist stores a playlist with matched songs in order, unmatched (0) IDs are left out
+func createPlaylist(tx *gorm.DB, name, userId string, songIds []int) (*models.Playlist, error) {
+	p := &models.Playlist{Name: name, UserID: userId}
+	if err := tx.Create(p).Error; err != nil {
+		return nil, err
+	}
+	var entries []models.PlaylistEntry
+	for _, id := range songIds {
+		if id != 0 {
+			entries = append(entries, models.PlaylistEntry{PlaylistID: p.ID, Position: len(entries), SongID: id})
+		}
+	}
+	if len(entries) > 0 {
+		if err := tx.CreateInBatches(&entries, 500).Error; err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// playlistSongs loads songs of a playlist in order, deleted songs are skipped
+func playlistSongs(db *gorm.DB, playlistId int) ([]models.Song, error) {
+	var entries []models.PlaylistEntry
+	if err := db.Where("playlist_id = ?", playlistId).Order("position").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.SongID
+	}
+	return songsInOrder(db, ids)
+}
+
+// songsInOrder loads songs by IDs keeping the order and repeats of ids
+func songsInOrder(db *gorm.DB, ids []int) ([]models.Song, error) {
+	songs := []models.Song{}
+	if len(ids) == 0 {
+		return songs, nil
+	}
+	var found []models.Song
+	if err := db.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Song, len(found))
+	for _, s := range found {
+		byID[s.ID] = s
+	}
+	for _, id := range ids {
+		if s, ok := byID[id]; ok {
+			songs = append(songs, s)
+		}
+	}
+	return songs, nil
+}
+
+// @Summary Получить плейлист
+// @Description Возвращает плейлист и его песни по порядку
+// @Tags Playlists
+// @Produce json
+// @Param playlistId query int true "ID плейлиста"
+// @Success 200 {object} map[string]interface{} "playlist и songs"
+// @Failure 400 {string} string "Некорректный ID плейлиста"
+// @Failure 404 {string} string "Плейлист не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/GetPlaylist [get]
+func GetPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetPlaylist> endpoint...")
+
+	playlistIntId, err := strconv.Atoi(r.URL.Query().Get("playlistId"))
+	if err != nil {
+		log.Printf("Error: Invalid playlistId parameter: %v", err)
+		http.Error(w, "Invalid playlistId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var p models.Playlist
+	if err := db.First(&p, playlistIntId).Error; err != nil {
+		log.Printf("Error: Playlist with id %d not found", playlistIntId)
+		http.Error(w, "Playlist not found", http.StatusNotFound)
+		return
+	}
+	songs, err := playlistSongs(db, p.ID)
+	if err == nil {
+		err = attachCovers(db, songs)
+	}
+	if err == nil {
+		err = attachLinks(db, songs)
+	}
+	if err != nil {
+		log.Printf("Error: Failed to load songs of playlist %d: %v", p.ID, err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"playlist": p,
+		"songs":    songs,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Импорт плейлиста
+// @Description Читает файл плейлиста (M3U8, XSPF, PLS), ищет песни в библиотеке по исполнителю и названию (или по ссылке) и создает плейлист из найденных. В ответе - записи, для которых песня не найдена
+// @Tags Playlists
+// @Accept plain
+// @Produce json
+// @Param X-User-Id header string false "Владелец плейлиста"
+// @Param format query string true "m3u8, xspf или pls"
+// @Param name query string false "Название плейлиста (по умолчанию из файла)"
+// @Param body body string true "Содержимое файла плейлиста"
+// @Success 201 {object} map[string]interface{} "playlist, matched - число найденных песен, unmatched - ненайденные записи"
+// @Failure 400 {string} string "Некорректный формат или файл"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/ImportPlaylist [post]
+func ImportPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ImportPlaylist> endpoint...")
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if _, ok := playlist.ContentTypes[format]; !ok {
+		log.Printf("Error: Unknown playlist format %q", format)
+		http.Error(w, "Unknown format, use m3u8, xspf or pls", http.StatusBadRequest)
+		return
+	}
+
+	title, entries, err := playlist.Parse(format, http.MaxBytesReader(w, r.Body, maxPlaylistSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Error: Playlist file is larger than %d bytes", maxPlaylistSize)
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error: Invalid playlist file: %v", err)
+		http.Error(w, "Invalid playlist file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		name = title
+	}
+	if name == "" {
+		name = "Imported playlist"
+	}
+
+	db := database.GetDB()
+	var p *models.Playlist
+	var unmatched []unmatchedEntry
+	err = db.Transaction(func(tx *gorm.DB) error {
+		ids, notFound, err := matchEntries(tx, entries)
+		if err != nil {
+			return err
+		}
+		unmatched = notFound
+		p, err = createPlaylist(tx, name, r.Header.Get(userIDHeader), ids)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error: Failed to import playlist: %v", err)
+		http.Error(w, "Error saving playlist to database: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Playlist %d imported: %d of %d entries matched", p.ID, len(entries)-len(unmatched), len(entries))
+
+	if unmatched == nil {
+		unmatched = []unmatchedEntry{}
+	}
+	response := map[string]interface{}{
+		"playlist":  p,
+		"entries":   len(entries),
+		"matched":   len(entries) - len(unmatched),
+		"unmatched": unmatched,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/GetPlaylist?playlistId=%d", p.ID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Экспорт плейлиста
+// @Description Выгружает плейлист (playlistId) или список песен (songIds) в формате M3U8, XSPF или PLS, адрес трека - ссылка песни (link). В M3U8 и PLS песни без ссылки пропускаются, их число - в заголовке X-Skipped-Entries
+// @Tags Playlists
+// @Produce plain
+// @Param format query string true "m3u8, xspf или pls"
+// @Param playlistId query int false "ID плейлиста"
+// @Param songIds query string false "ID песен через запятую, если playlistId не указан"
+// @Success 200 {string} string "Файл плейлиста"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 404 {string} string "Плейлист не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/ExportPlaylist [get]
+func ExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ExportPlaylist> endpoint...")
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	contentType, ok := playlist.ContentTypes[format]
+	if !ok {
+		log.Printf("Error: Unknown playlist format %q", format)
+		http.Error(w, "Unknown format, use m3u8, xspf or pls", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var songs []models.Song
+	title := "songs"
+	if v := r.URL.Query().Get("playlistId"); v != "" {
+		playlistIntId, err := strconv.Atoi(v)
+		if err != nil {
+			log.Printf("Error: Invalid playlistId parameter: %v", err)
+			http.Error(w, "Invalid playlistId parameter", http.StatusBadRequest)
+			return
+		}
+		var p models.Playlist
+		if err := db.First(&p, playlistIntId).Error; err != nil {
+			log.Printf("Error: Playlist with id %d not found", playlistIntId)
+			http.Error(w, "Playlist not found", http.StatusNotFound)
+			return
+		}
+		title = p.Name
+		if songs, err = playlistSongs(db, p.ID); err != nil {
+			log.Printf("Error: Failed to load songs of playlist %d: %v", p.ID, err)
+			http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		var ids []int
+		for _, v := range strings.Split(r.URL.Query().Get("songIds"), ",") {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			id, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				log.Printf("Error: Invalid songIds parameter: %v", err)
+				http.Error(w, "Invalid songIds parameter", http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, id)
+		}
+		if len(ids) == 0 {
+			log.Println("Error: Missing playlistId and songIds parameters")
+			http.Error(w, "playlistId or songIds is required", http.StatusBadRequest)
+			return
+		}
+		var err error
+		if songs, err = songsInOrder(db, ids); err != nil {
+			log.Printf("Error: Failed to load songs: %v", err)
+			http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	entries := make([]playlist.Entry, len(songs))
+	for i, s := range songs {
+		entries[i] = playlist.Entry{Artist: s.Group, Title: s.Song, Location: s.Link, Duration: -1}
+	}
+
+	var body strings.Builder
+	skipped, err := playlist.Write(format, &body, title, entries)
+	if err != nil {
+		log.Printf("Error: Failed to write playlist: %v", err)
+		http.Error(w, "Failed to write playlist", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Exported %d songs as %s, %d without link skipped", len(songs)-skipped, format, skipped)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"playlist.%s\"", format))
+	w.Header().Set("X-Skipped-Entries", strconv.Itoa(skipped))
+	w.Write([]byte(body.String()))
+}
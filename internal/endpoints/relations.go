@@ -0,0 +1,385 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxFamilySize = 1000 // songs in a versions response
+
+// relationRequest body of AddSongRelation: fromSongId is a <type> of toSongId
+type relationRequest struct {
+	FromSongID int    `json:"fromSongId"`
+	ToSongID   int    `json:"toSongId"`
+	Type       string `json:"type" example:"cover-of"`
+}
+
+var (
+	errRelationExists = errors.New("songs already have this relation")
+	errRelationCycle  = errors.New("relation would make a cycle, a song cannot be derived from itself")
+)
+
+func knownRelation(t string) bool {
+	for _, known := range models.RelationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// existingSongs limits relations to songs that were not deleted
+const existingSongs = "EXISTS (SELECT 1 FROM songs WHERE songs.id = song_relations.from_song_id) AND " +
+	"EXISTS (SELECT 1 FROM songs WHERE songs.id = song_relations.to_song_id)"
+
+// derives tells whether a path of directed relations leads from one song to another
+func derives(tx *gorm.DB, from, to int) (bool, error) {
+	seen := map[int]bool{from: true}
+	frontier := []int{from}
+	for len(frontier) > 0 {
+		var targets []int
+		err := tx.Model(&models.SongRelation{}).
+			Where("from_song_id IN ? AND type <> ?", frontier, models.RelationSameComposition).
+			Pluck("to_song_id", &targets).Error
+		if err != nil {
+			return false, err
+		}
+		frontier = nil
+		for _, id := range targets {
+			if id == to {
+				return true, nil
+			}
+			if !seen[id] {
+				seen[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// family collects songs connected to the song by relations of any direction, at most maxFamilySize of them
+func family(db *gorm.DB, songId int, types []string) ([]int, []models.SongRelation, bool, error) {
+	ids := []int{songId}
+	seen := map[int]bool{songId: true}
+	seenRelations := map[int]bool{}
+	var relations []models.SongRelation
+	truncated := false
+
+	frontier := []int{songId}
+	for len(frontier) > 0 {
+		query := db.Where("(from_song_id IN ? OR to_song_id IN ?) AND "+existingSongs, frontier, frontier)
+		if len(types) > 0 {
+			query = query.Where("type IN ?", types)
+		}
+		var rows []models.SongRelation
+		if err := query.Order("id").Find(&rows).Error; err != nil {
+			return nil, nil, false, err
+		}
+		frontier = nil
+		for _, rel := range rows {
+			if seenRelations[rel.ID] {
+				continue
+			}
+			seenRelations[rel.ID] = true
+			for _, id := range []int{rel.FromSongID, rel.ToSongID} {
+				if seen[id] {
+					continue
+				}
+				if len(ids) >= maxFamilySize {
+					truncated = true
+					continue
+				}
+				seen[id] = true
+				ids = append(ids, id)
+				frontier = append(frontier, id)
+			}
+			if seen[rel.FromSongID] && seen[rel.ToSongID] {
+				relations = append(relations, rel)
+			}
+		}
+	}
+	return ids, relations, truncated, nil
+}
+
+// moveRelations points relations of the duplicate to the song, relations between the two
+// and ones the song already has are discarded
+func moveRelations(tx *gorm.DB, songId, duplicateId int) (moved, discarded int, err error) {
+	clashes := []*gorm.DB{
+		tx.Where("(from_song_id = ? AND to_song_id = ?) OR (from_song_id = ? AND to_song_id = ?)", songId, duplicateId, duplicateId, songId),
+		tx.Where("from_song_id = ? AND (to_song_id, type) IN (?)", duplicateId,
+			tx.Model(&models.SongRelation{}).Select("to_song_id, type").Where("from_song_id = ?", songId)),
+		tx.Where("to_song_id = ? AND (from_song_id, type) IN (?)", duplicateId,
+			tx.Model(&models.SongRelation{}).Select("from_song_id, type").Where("to_song_id = ?", songId)),
+		// the same composition stored in the other direction
+		tx.Where("type = ? AND from_song_id = ? AND to_song_id IN (?)", models.RelationSameComposition, duplicateId,
+			tx.Model(&models.SongRelation{}).Select("from_song_id").Where("type = ? AND to_song_id = ?", models.RelationSameComposition, songId)),
+		tx.Where("type = ? AND to_song_id = ? AND from_song_id IN (?)", models.RelationSameComposition, duplicateId,
+			tx.Model(&models.SongRelation{}).Select("to_song_id").Where("type = ? AND from_song_id = ?", models.RelationSameComposition, songId)),
+	}
+	for _, clash := range clashes {
+		res := clash.Delete(&models.SongRelation{})
+		if res.Error != nil {
+			return 0, 0, res.Error
+		}
+		discarded += int(res.RowsAffected)
+	}
+	for _, column := range []string{"from_song_id", "to_song_id"} {
+		res := tx.Model(&models.SongRelation{}).Where(column+" = ?", duplicateId).Update(column, songId)
+		if res.Error != nil {
+			return 0, 0, res.Error
+		}
+		moved += int(res.RowsAffected)
+	}
+	return moved, discarded, nil
+}
+
+// @Summary Связать песни
+// @Description Создает направленную связь fromSongId -> toSongId: cover-of (кавер), remix-of (ремикс), live-version-of (концертная версия), samples (использует семпл) или same-composition (то же произведение, связь симметрична).
+// @Description Направленные связи не могут образовать цикл: песня не может происходить от самой себя
+// @Tags Relations
+// @Accept json
+// @Produce json
+// @Param body body relationRequest true "Песни и тип связи"
+// @Success 201 {object} models.SongRelation "Созданная связь"
+// @Failure 400 {string} string "Некорректный запрос, неизвестный тип или цикл"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 409 {object} map[string]interface{} "Связь уже есть"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/AddSongRelation [post]
+func AddSongRelation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <AddSongRelation> endpoint...")
+
+	var req relationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if !knownRelation(req.Type) {
+		log.Printf("Error: Unknown relation type %q", req.Type)
+		http.Error(w, "Unknown type, use one of "+strings.Join(models.RelationTypes, ", "), http.StatusBadRequest)
+		return
+	}
+	if req.FromSongID <= 0 || req.ToSongID <= 0 || req.FromSongID == req.ToSongID {
+		log.Printf("Error: Invalid songs of relation: %d -> %d", req.FromSongID, req.ToSongID)
+		http.Error(w, "fromSongId and toSongId must be two different songs", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var found int64
+	if err := db.Model(&models.Song{}).Where("id IN ?", []int{req.FromSongID, req.ToSongID}).Count(&found).Error; err != nil {
+		log.Printf("Error: Failed to find songs: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if found != 2 {
+		log.Printf("Error: Song %d or %d not found", req.FromSongID, req.ToSongID)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	relation := models.SongRelation{FromSongID: req.FromSongID, ToSongID: req.ToSongID, Type: req.Type}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if req.Type == models.RelationSameComposition {
+			var reverse int64
+			err := tx.Model(&models.SongRelation{}).
+				Where("from_song_id = ? AND to_song_id = ? AND type = ?", req.ToSongID, req.FromSongID, req.Type).
+				Count(&reverse).Error
+			if err != nil {
+				return err
+			}
+			if reverse > 0 {
+				return errRelationExists
+			}
+		} else {
+			cycle, err := derives(tx, req.ToSongID, req.FromSongID)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return errRelationCycle
+			}
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&relation)
+		if res.Error == nil && res.RowsAffected == 0 {
+			return errRelationExists
+		}
+		return res.Error
+	})
+	if errors.Is(err, errRelationCycle) {
+		log.Printf("Error: Relation %d %s %d makes a cycle", req.FromSongID, req.Type, req.ToSongID)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errRelationExists) {
+		log.Printf("Info: Relation %d %s %d already exists", req.FromSongID, req.Type, req.ToSongID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error: Failed to save relation: %v", err)
+		http.Error(w, "Error saving to database: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Info: Relation %d saved: song %d %s song %d", relation.ID, relation.FromSongID, relation.Type, relation.ToSongID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(relation)
+}
+
+// @Summary Удалить связь песен
+// @Description Удаляет связь (relationId)
+// @Tags Relations
+// @Produce json
+// @Param relationId query int true "ID связи"
+// @Success 200 {object} map[string]interface{} "deleted: true"
+// @Failure 400 {string} string "Некорректный ID связи"
+// @Failure 404 {string} string "Связь не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Failure 500 {string} string "Ошибка сервера при удалении"
+// @Router /api/DeleteSongRelation [delete]
+func DeleteSongRelation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteSongRelation> endpoint...")
+
+	relationIntId, err := strconv.Atoi(r.URL.Query().Get("relationId"))
+	if err != nil {
+		log.Printf("Error: Invalid relationId parameter: %v", err)
+		http.Error(w, "Invalid relationId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	res := db.Delete(&models.SongRelation{}, relationIntId)
+	if res.Error != nil {
+		log.Printf("Error: Failed to delete relation %d: %v", relationIntId, res.Error)
+		http.Error(w, "Failed to delete relation", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		log.Printf("Error: Relation with id %d not found", relationIntId)
+		http.Error(w, "Relation not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Info: Relation %d deleted", relationIntId)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deleted": true})
+}
+
+// @Summary Версии песни
+// @Description Возвращает всю семью песни: песни, связанные с ней связями любого направления и через другие песни (каверы каверов, ремиксы концертных версий и т.д.), и сами связи. originals - песни семьи, которые не являются кавером, ремиксом или концертной версией другой песни семьи
+// @Tags Relations
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param types query string false "Учитывать только эти типы связей через запятую, например cover-of,remix-of"
+// @Success 200 {object} map[string]interface{} "songId, songs, relations (models.SongRelation), originals, truncated - семья больше 1000 песен"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректные параметры"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка чтения из БД"
+// @Router /api/GetSongVersions [get]
+func GetSongVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetSongVersions> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	var types []string
+	if v := r.URL.Query().Get("types"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if !knownRelation(t) {
+				log.Printf("Error: Unknown relation type %q", t)
+				http.Error(w, "Unknown type, use "+strings.Join(models.RelationTypes, ", "), http.StatusBadRequest)
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		if redirectMerged(w, r, db, songIntId) {
+			return
+		}
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	ids, relations, truncated, err := family(db, song.ID, types)
+	var songs []models.Song
+	if err == nil {
+		err = db.Where("id IN ?", ids).Order("id").Find(&songs).Error
+	}
+	if err == nil {
+		err = attachCovers(db, songs)
+	}
+	if err != nil {
+		log.Printf("Error: Failed to load versions of song %d: %v", song.ID, err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	derived := map[int]bool{}
+	for _, rel := range relations {
+		switch rel.Type {
+		case models.RelationCoverOf, models.RelationRemixOf, models.RelationLiveVersionOf:
+			derived[rel.FromSongID] = true
+		}
+	}
+	originals := []int{}
+	for _, s := range songs {
+		if !derived[s.ID] {
+			originals = append(originals, s.ID)
+		}
+	}
+	if relations == nil {
+		relations = []models.SongRelation{}
+	}
+	log.Printf("Info: Song %d has %d versions in its family, %d relations", song.ID, len(songs)-1, len(relations))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"songId":    song.ID,
+		"songs":     songs,
+		"relations": relations,
+		"originals": originals,
+		"truncated": truncated,
+	})
+}
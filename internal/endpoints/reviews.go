@@ -0,0 +1,415 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// reviewRequest body of AddReview and EditReview
+type reviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+func (rr reviewRequest) validate() string {
+	if rr.Rating < 1 || rr.Rating > 5 {
+		return "Rating must be from 1 to 5"
+	}
+	if len([]rune(rr.Text)) > 5000 {
+		return "Review text is too long (max 5000 characters)"
+	}
+	return ""
+}
+
+// attachRatings fills RatingAvg and RatingCount from visible reviews
+func attachRatings(db *gorm.DB, songs []models.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	ids := make([]int, len(songs))
+	for i, s := range songs {
+		ids[i] = s.ID
+	}
+
+	var stats []struct {
+		SongID int
+		Avg    float64
+		Count  int
+	}
+	err := db.Model(&models.Review{}).
+		Select("song_id, AVG(rating) AS avg, COUNT(*) AS count").
+		Where("song_id IN ? AND hidden = ?", ids, false).
+		Group("song_id").Scan(&stats).Error
+	if err != nil {
+		return err
+	}
+
+	bySong := make(map[int]int, len(stats))
+	for i, st := range stats {
+		bySong[st.SongID] = i
+	}
+	for i := range songs {
+		if j, ok := bySong[songs[i].ID]; ok {
+			songs[i].RatingAvg = stats[j].Avg
+			songs[i].RatingCount = stats[j].Count
+		}
+	}
+	return nil
+}
+
+// @Summary Оценить песню
+// @Description Добавляет оценку (1-5) и необязательный отзыв пользователя из заголовка X-User-Id. Один отзыв на песню от пользователя
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param X-User-Id header string true "ID пользователя"
+// @Param body body reviewRequest true "Оценка и текст отзыва"
+// @Success 201 {object} models.Review "Отзыв добавлен"
+// @Failure 400 {string} string "Некорректные параметры или оценка вне диапазона"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется POST)"
+// @Failure 409 {string} string "Пользователь уже оставил отзыв на эту песню"
+// @Failure 500 {string} string "Ошибка сервера"
+// @Router /api/AddReview [post]
+func AddReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("Error: Invalid request method. Expected POST, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <AddReview> endpoint...")
+
+	userId := r.Header.Get(userIDHeader)
+	if userId == "" {
+		log.Println("Error: Missing X-User-Id header")
+		http.Error(w, "Missing X-User-Id header", http.StatusBadRequest)
+		return
+	}
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		log.Printf("Error: Invalid review: %s", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.Review{}).Where("song_id = ? AND user_id = ?", song.ID, userId).Count(&count).Error; err != nil {
+		log.Printf("Error: Failed to check reviews of song %d: %v", song.ID, err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		log.Printf("Error: User %s already reviewed song %d", userId, song.ID)
+		http.Error(w, "You have already reviewed this song", http.StatusConflict)
+		return
+	}
+
+	review := models.Review{SongID: song.ID, UserID: userId, Rating: req.Rating, Text: strings.TrimSpace(req.Text)}
+	if err := db.Create(&review).Error; err != nil {
+		// a parallel request of the same user passed the check too
+		if database.IsUniqueViolation(err) {
+			log.Printf("Error: User %s already reviewed song %d", userId, song.ID)
+			http.Error(w, "You have already reviewed this song", http.StatusConflict)
+			return
+		}
+		log.Printf("Error: Failed to save review: %v", err)
+		http.Error(w, "Failed to save review", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Review %d added to song %d", review.ID, song.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// @Summary Редактировать свой отзыв
+// @Description Обновляет оценку и текст отзыва. Редактировать можно только свой отзыв
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param reviewId query int true "ID отзыва"
+// @Param X-User-Id header string true "ID пользователя"
+// @Param body body reviewRequest true "Новые оценка и текст отзыва"
+// @Success 200 {object} models.Review "Отзыв обновлен"
+// @Failure 400 {string} string "Некорректные параметры или оценка вне диапазона"
+// @Failure 403 {string} string "Отзыв принадлежит другому пользователю"
+// @Failure 404 {string} string "Отзыв не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется PUT)"
+// @Router /api/EditReview [put]
+func EditReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		log.Printf("Error: Invalid request method. Expected PUT, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <EditReview> endpoint...")
+
+	userId := r.Header.Get(userIDHeader)
+	if userId == "" {
+		log.Println("Error: Missing X-User-Id header")
+		http.Error(w, "Missing X-User-Id header", http.StatusBadRequest)
+		return
+	}
+
+	reviewIntId, err := strconv.Atoi(r.URL.Query().Get("reviewId"))
+	if err != nil {
+		log.Printf("Error: Invalid reviewId parameter: %v", err)
+		http.Error(w, "Invalid reviewId parameter", http.StatusBadRequest)
+		return
+	}
+
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		log.Printf("Error: Invalid review: %s", msg)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var review models.Review
+	if err := db.First(&review, reviewIntId).Error; err != nil {
+		log.Printf("Error: Review with id %d not found", reviewIntId)
+		http.Error(w, "Review not found", http.StatusNotFound)
+		return
+	}
+	if review.UserID != userId {
+		log.Printf("Error: User %s tried to edit review %d of %s", userId, review.ID, review.UserID)
+		http.Error(w, "You can edit only your own review", http.StatusForbidden)
+		return
+	}
+
+	review.Rating = req.Rating
+	review.Text = strings.TrimSpace(req.Text)
+	if err := db.Save(&review).Error; err != nil {
+		log.Printf("Error: Failed to update review %d: %v", review.ID, err)
+		http.Error(w, "Failed to update review", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Review %d updated successfully", review.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// @Summary Удалить отзыв
+// @Description Удаляет отзыв. Пользователь может удалить только свой отзыв, администратор - любой
+// @Tags Reviews
+// @Produce json
+// @Param reviewId query int true "ID отзыва"
+// @Param X-User-Id header string false "ID пользователя"
+// @Param X-Admin-Token header string false "Токен администратора"
+// @Success 200 {object} models.Review "Отзыв удален"
+// @Failure 400 {string} string "Некорректный ID отзыва"
+// @Failure 403 {string} string "Отзыв принадлежит другому пользователю"
+// @Failure 404 {string} string "Отзыв не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Router /api/DeleteReview [delete]
+func DeleteReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteReview> endpoint...")
+
+	reviewIntId, err := strconv.Atoi(r.URL.Query().Get("reviewId"))
+	if err != nil {
+		log.Printf("Error: Invalid reviewId parameter: %v", err)
+		http.Error(w, "Invalid reviewId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var review models.Review
+	if err := db.First(&review, reviewIntId).Error; err != nil {
+		log.Printf("Error: Review with id %d not found", reviewIntId)
+		http.Error(w, "Review not found", http.StatusNotFound)
+		return
+	}
+
+	userId := r.Header.Get(userIDHeader)
+	if !isAdmin(r) && (userId == "" || review.UserID != userId) {
+		log.Printf("Error: User %q tried to delete review %d of %s", userId, review.ID, review.UserID)
+		http.Error(w, "You can delete only your own review", http.StatusForbidden)
+		return
+	}
+
+	if err := db.Delete(&review).Error; err != nil {
+		log.Printf("Error: Failed to delete review %d: %v", review.ID, err)
+		http.Error(w, "Failed to delete review", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Review %d deleted successfully", review.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// @Summary Получить отзывы песни
+// @Description Возвращает отзывы песни постранично, новые первыми. Скрытые отзывы видны только администратору
+// @Tags Reviews
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param page query int false "Номер страницы (по умолчанию 1)"
+// @Param limit query int false "Количество отзывов на странице (по умолчанию 10)"
+// @Param X-Admin-Token header string false "Токен администратора"
+// @Success 200 {object} map[string]interface{} "Отзывы постранично"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/GetReviews [get]
+func GetReviews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetReviews> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	db := database.GetDB()
+	if redirectMerged(w, r, db, songIntId) {
+		return
+	}
+	query := db.Model(&models.Review{}).Where("song_id = ?", songIntId)
+	if !isAdmin(r) {
+		query = query.Where("hidden = ?", false)
+	}
+	query = query.Session(&gorm.Session{}) // reused for count and page
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("Error: Failed to count reviews: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var reviews []models.Review
+	err = query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&reviews).Error
+	if err != nil {
+		log.Printf("Error: Failed to find reviews: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"reviews": reviews,
+		"page":    page,
+		"perPage": limit,
+		"total":   total,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary Скрыть или показать отзыв (модерация)
+// @Description Администратор скрывает отзыв из выдачи и из рейтинга песни или возвращает его
+// @Tags Reviews
+// @Produce json
+// @Param reviewId query int true "ID отзыва"
+// @Param hidden query bool true "true - скрыть, false - показать"
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 200 {object} models.Review "Отзыв обновлен"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 403 {string} string "Требуются права администратора"
+// @Failure 404 {string} string "Отзыв не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется PUT)"
+// @Router /api/ModerateReview [put]
+func ModerateReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		log.Printf("Error: Invalid request method. Expected PUT, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <ModerateReview> endpoint...")
+
+	if !isAdmin(r) {
+		log.Println("Error: ModerateReview called without admin token")
+		http.Error(w, "Admin rights required", http.StatusForbidden)
+		return
+	}
+
+	reviewIntId, err := strconv.Atoi(r.URL.Query().Get("reviewId"))
+	if err != nil {
+		log.Printf("Error: Invalid reviewId parameter: %v", err)
+		http.Error(w, "Invalid reviewId parameter", http.StatusBadRequest)
+		return
+	}
+	hidden, err := strconv.ParseBool(r.URL.Query().Get("hidden"))
+	if err != nil {
+		log.Printf("Error: Invalid hidden parameter: %v", err)
+		http.Error(w, "Invalid hidden parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var review models.Review
+	if err := db.First(&review, reviewIntId).Error; err != nil {
+		log.Printf("Error: Review with id %d not found", reviewIntId)
+		http.Error(w, "Review not found", http.StatusNotFound)
+		return
+	}
+
+	review.Hidden = hidden
+	if err := db.Save(&review).Error; err != nil {
+		log.Printf("Error: Failed to moderate review %d: %v", review.ID, err)
+		http.Error(w, "Failed to update review", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Review %d hidden=%t", review.ID, hidden)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
@@ -0,0 +1,71 @@
+package endpoints
+
+import (
+	"fmt"
+	"music_library_api/internal/links"
+	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
+	"strings"
+)
+
+const maxTextLength = 20000
+
+// addSongRequest body of AddSong, releaseDate, text and link are used only in manual mode
+type addSongRequest struct {
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	Genre       string `json:"genre"`
+	Album       string `json:"album"`
+	ReleaseDate string `json:"releaseDate" example:"2006-07-16"` // also YYYY-MM, YYYY, DD.MM.YYYY, "Sep 1991"
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// toSong validates the request, client release date, text and link are kept only if manual
+func (req addSongRequest) toSong(manual bool) (models.Song, error) {
+	song := models.Song{
+		Group: strings.TrimSpace(req.Group),
+		Song:  strings.TrimSpace(req.Song),
+		Genre: strings.TrimSpace(req.Genre),
+		Album: strings.TrimSpace(req.Album),
+	}
+	if song.Group == "" || song.Song == "" {
+		return song, fmt.Errorf("fields group and song are required")
+	}
+	if !manual {
+		return song, nil
+	}
+
+	if req.ReleaseDate != "" {
+		date, precision, err := releasedate.Parse(req.ReleaseDate)
+		if err != nil {
+			return song, fmt.Errorf("invalid releaseDate: %w", err)
+		}
+		song.ReleaseDate = date
+		song.ReleaseDatePrecision = precision
+	}
+
+	if len([]rune(req.Text)) > maxTextLength {
+		return song, fmt.Errorf("text is too long (max %d characters)", maxTextLength)
+	}
+	song.Text = req.Text
+
+	link, err := canonicalLink(req.Link)
+	if err != nil {
+		return song, err
+	}
+	song.Link = link
+	return song, nil
+}
+
+// canonicalLink validates a link by the rules of its platform, empty stays empty
+func canonicalLink(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	link, err := links.Parse(raw, "")
+	if err != nil {
+		return "", err
+	}
+	return link.URL, nil
+}
@@ -0,0 +1,233 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/lyrics"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const maxLRCSize = 1 << 20 // 1 MB
+
+// loadSyncedLyrics reads and parses stored LRC of a song
+func loadSyncedLyrics(w http.ResponseWriter, r *http.Request) (*models.SyncedLyrics, *lyrics.LRC, bool) {
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	db := database.GetDB()
+	var synced models.SyncedLyrics
+	if err := db.Where("song_id = ?", songIntId).First(&synced).Error; err != nil {
+		if redirectMerged(w, r, db, songIntId) {
+			return nil, nil, false
+		}
+		log.Printf("Error: Synced lyrics for song %d not found", songIntId)
+		http.Error(w, "Synced lyrics not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	lrc, err := lyrics.ParseLRC(synced.Raw)
+	if err != nil { // validated on upload, so this is a broken row
+		log.Printf("Error: Stored LRC of song %d is invalid: %v", songIntId, err)
+		http.Error(w, "Stored synced lyrics are invalid", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return &synced, lrc, true
+}
+
+// @Summary Загрузить синхронизированный текст (LRC)
+// @Description Загружает LRC или enhanced LRC (с таймингом слов) для песни. Текст проверяется и сохраняется рядом с обычным текстом песни, повторная загрузка заменяет предыдущую
+// @Tags Lyrics
+// @Accept plain
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param body body string true "Содержимое LRC файла"
+// @Success 200 {object} lyrics.LRC "Разобранный LRC"
+// @Failure 400 {string} string "Некорректный ID песни или невалидный LRC"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется PUT)"
+// @Failure 413 {string} string "Слишком большой файл"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/UploadSyncedLyrics [put]
+func UploadSyncedLyrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		log.Printf("Error: Invalid request method. Expected PUT, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <UploadSyncedLyrics> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLRCSize))
+	if err != nil {
+		log.Printf("Error: Failed to read LRC body: %v", err)
+		http.Error(w, "LRC file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	lrc, err := lyrics.ParseLRC(string(body))
+	if err != nil {
+		log.Printf("Error: Invalid LRC: %v", err)
+		http.Error(w, "Invalid LRC: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	var synced models.SyncedLyrics
+	err = db.Where("song_id = ?", song.ID).First(&synced).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error: Failed to find synced lyrics: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	synced.SongID = song.ID
+	synced.Raw = string(body)
+	synced.Enhanced = lrc.Enhanced
+	if err := db.Save(&synced).Error; err != nil {
+		log.Printf("Error: Failed to save synced lyrics: %v", err)
+		http.Error(w, "Failed to save synced lyrics", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Synced lyrics of song %d saved, %d lines, enhanced: %t", song.ID, len(lrc.Lines), lrc.Enhanced)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lrc)
+}
+
+// @Summary Получить синхронизированный текст
+// @Description Возвращает синхронизированный текст песни в JSON (начало и конец строк в мс) или исходный LRC
+// @Tags Lyrics
+// @Produce json
+// @Produce plain
+// @Param songId query int true "ID песни"
+// @Param format query string false "json (по умолчанию) или lrc"
+// @Success 200 {object} lyrics.LRC "Синхронизированный текст"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 404 {string} string "Синхронизированный текст не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Router /api/GetSyncedLyrics [get]
+func GetSyncedLyrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetSyncedLyrics> endpoint...")
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "lrc" {
+		log.Printf("Error: Unknown format %q", format)
+		http.Error(w, "Unknown format, use json or lrc", http.StatusBadRequest)
+		return
+	}
+
+	synced, lrc, ok := loadSyncedLyrics(w, r)
+	if !ok {
+		return
+	}
+
+	if format == "lrc" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, synced.Raw)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lrc)
+}
+
+// @Summary Строка текста в момент времени
+// @Description Возвращает строку, которая звучит в момент t (мс), а также соседние строки для плеера
+// @Tags Lyrics
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param t query int true "Время от начала песни в мс"
+// @Param context query int false "Количество следующих строк (по умолчанию 1)"
+// @Success 200 {object} map[string]interface{} "Текущая и следующие строки"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 404 {string} string "Синхронизированный текст не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Router /api/GetLyricsAtTime [get]
+func GetLyricsAtTime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetLyricsAtTime> endpoint...")
+
+	t, err := strconv.Atoi(r.URL.Query().Get("t"))
+	if err != nil || t < 0 {
+		log.Printf("Error: Invalid t parameter: %q", r.URL.Query().Get("t"))
+		http.Error(w, "Invalid t parameter", http.StatusBadRequest)
+		return
+	}
+	next, err := strconv.Atoi(r.URL.Query().Get("context"))
+	if err != nil || next < 0 {
+		next = 1
+	}
+
+	_, lrc, ok := loadSyncedLyrics(w, r)
+	if !ok {
+		return
+	}
+
+	index := lrc.At(t)
+
+	// next lines after the current one, or after t between lines
+	from := index + 1
+	if index < 0 {
+		from = len(lrc.Lines)
+		for i, l := range lrc.Lines {
+			if l.StartMs > t {
+				from = i
+				break
+			}
+		}
+	}
+	// clamp before adding, a huge context would overflow from + next
+	if next > len(lrc.Lines)-from {
+		next = len(lrc.Lines) - from
+	}
+	to := from + next
+
+	var current *lyrics.SyncedLine
+	if index >= 0 {
+		current = &lrc.Lines[index]
+	}
+
+	response := map[string]interface{}{
+		"t":       t,
+		"index":   index,
+		"current": current,
+		"next":    lrc.Lines[from:to],
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,221 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"music_library_api/internal/database"
+	"music_library_api/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+)
+
+// translationRequest body of UploadTranslation
+type translationRequest struct {
+	Text       string `json:"text"`
+	Translator string `json:"translator"`
+}
+
+// alignedVerse is one verse of side-by-side lyrics
+type alignedVerse struct {
+	Index       int    `json:"index"`
+	Original    string `json:"original"`
+	Translation string `json:"translation"`
+}
+
+// findTranslation picks the best stored translation for a requested language,
+// e.g. "pt" matches "pt-BR" and "en-GB" matches "en". nil means no match.
+func findTranslation(db *gorm.DB, songId int, requested language.Tag) (*models.Translation, error) {
+	var translations []models.Translation
+	if err := db.Where("song_id = ?", songId).Find(&translations).Error; err != nil {
+		return nil, err
+	}
+	if len(translations) == 0 {
+		return nil, nil
+	}
+
+	tags := make([]language.Tag, len(translations))
+	for i, t := range translations {
+		tags[i] = language.Make(t.Lang)
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(requested)
+	if confidence < language.High {
+		return nil, nil
+	}
+	return &translations[index], nil
+}
+
+// @Summary Загрузить перевод текста песни
+// @Description Добавляет или заменяет перевод текста песни на язык lang (BCP-47) с указанием переводчика. Строфы разделяются пустой строкой, как в оригинале
+// @Tags Translations
+// @Accept json
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param lang query string true "Язык перевода (BCP-47), например ru, en, pt-BR"
+// @Param body body translationRequest true "Текст перевода и переводчик"
+// @Success 200 {object} models.Translation "Перевод сохранен"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 404 {string} string "Песня не найдена"
+// @Failure 405 {string} string "Неверный метод запроса (требуется PUT)"
+// @Failure 500 {string} string "Ошибка сервера при сохранении"
+// @Router /api/UploadTranslation [put]
+func UploadTranslation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		log.Printf("Error: Invalid request method. Expected PUT, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <UploadTranslation> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := language.Parse(r.URL.Query().Get("lang"))
+	if err != nil {
+		log.Printf("Error: Invalid lang parameter: %v", err)
+		http.Error(w, "Invalid lang parameter, use BCP-47 tag like ru or pt-BR", http.StatusBadRequest)
+		return
+	}
+
+	var req translationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error: Invalid JSON body: %v", err)
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		log.Println("Error: Empty translation text")
+		http.Error(w, "Translation text is required", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var song models.Song
+	if err := db.First(&song, songIntId).Error; err != nil {
+		log.Printf("Error: Song with id %d not found", songIntId)
+		http.Error(w, "Song not found", http.StatusNotFound)
+		return
+	}
+
+	var translation models.Translation
+	err = db.Where("song_id = ? AND lang = ?", song.ID, tag.String()).First(&translation).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error: Failed to find translation: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	translation.SongID = song.ID
+	translation.Lang = tag.String()
+	translation.Text = req.Text
+	translation.Translator = strings.TrimSpace(req.Translator)
+	if err := db.Save(&translation).Error; err != nil {
+		log.Printf("Error: Failed to save translation: %v", err)
+		http.Error(w, "Failed to save translation", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Translation %s of song %d saved", translation.Lang, song.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(translation)
+}
+
+// @Summary Список переводов песни
+// @Description Возвращает все переводы текста песни
+// @Tags Translations
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Success 200 {array} models.Translation "Переводы"
+// @Success 301 {string} string "Песня объединена с другой, Location - тот же запрос с новым songId"
+// @Failure 400 {string} string "Некорректный ID песни"
+// @Failure 405 {string} string "Неверный метод запроса (требуется GET)"
+// @Failure 500 {string} string "Ошибка сервера при получении данных из БД"
+// @Router /api/GetTranslations [get]
+func GetTranslations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("Error: Invalid request method. Expected GET, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <GetTranslations> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	if redirectMerged(w, r, db, songIntId) {
+		return
+	}
+	var translations []models.Translation
+	if err := db.Where("song_id = ?", songIntId).Order("lang").Find(&translations).Error; err != nil {
+		log.Printf("Error: Failed to find translations: %v", err)
+		http.Error(w, "Error getting data from DB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(translations)
+}
+
+// @Summary Удалить перевод
+// @Description Удаляет перевод текста песни на указанный язык
+// @Tags Translations
+// @Produce json
+// @Param songId query int true "ID песни"
+// @Param lang query string true "Язык перевода (BCP-47)"
+// @Success 200 {object} models.Translation "Перевод удален"
+// @Failure 400 {string} string "Некорректные параметры запроса"
+// @Failure 404 {string} string "Перевод не найден"
+// @Failure 405 {string} string "Неверный метод запроса (требуется DELETE)"
+// @Failure 500 {string} string "Ошибка сервера при удалении"
+// @Router /api/DeleteTranslation [delete]
+func DeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		log.Printf("Error: Invalid request method. Expected DELETE, got %s", r.Method)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Println("Info: <DeleteTranslation> endpoint...")
+
+	songIntId, err := strconv.Atoi(r.URL.Query().Get("songId"))
+	if err != nil {
+		log.Printf("Error: Invalid songId parameter: %v", err)
+		http.Error(w, "Invalid songId parameter", http.StatusBadRequest)
+		return
+	}
+	tag, err := language.Parse(r.URL.Query().Get("lang"))
+	if err != nil {
+		log.Printf("Error: Invalid lang parameter: %v", err)
+		http.Error(w, "Invalid lang parameter", http.StatusBadRequest)
+		return
+	}
+
+	db := database.GetDB()
+	var translation models.Translation
+	if err := db.Where("song_id = ? AND lang = ?", songIntId, tag.String()).First(&translation).Error; err != nil {
+		log.Printf("Error: Translation %s of song %d not found", tag, songIntId)
+		http.Error(w, "Translation not found", http.StatusNotFound)
+		return
+	}
+
+	if err := db.Delete(&translation).Error; err != nil {
+		log.Printf("Error: Failed to delete translation %d: %v", translation.ID, err)
+		http.Error(w, "Failed to delete translation", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Info: Translation %s of song %d deleted", translation.Lang, songIntId)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(translation)
+}
@@ -0,0 +1,127 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"music_library_api/internal/blob"
+	"net/http"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// upload is a request file spooled to a temporary file, hashed on the way
+type upload struct {
+	file   *os.File
+	size   int64
+	sha256 string
+	head   []byte // first bytes, for content sniffing
+	name   string // client file name, may be empty
+}
+
+// receiveUpload reads the field "file" of a multipart form or the raw body, at most limit bytes
+func receiveUpload(w http.ResponseWriter, r *http.Request, limit int64) (*upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, limit+64<<10) // form boundaries and headers
+	var src io.Reader = r.Body
+	u := &upload{}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		form, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			part, err := form.NextPart()
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("no file in the form")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if part.FormName() == "file" {
+				src, u.name = part, part.FileName()
+				break
+			}
+		}
+	}
+
+	file, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	u.file = file
+	hash := sha256.New()
+	// one byte over the limit tells a too large file from one of exactly limit bytes
+	u.size, err = io.Copy(io.MultiWriter(file, hash), io.LimitReader(src, limit+1))
+	if err == nil && u.size > limit {
+		err = &http.MaxBytesError{Limit: limit}
+	}
+	if err != nil {
+		u.Close()
+		return nil, err
+	}
+	u.sha256 = hex.EncodeToString(hash.Sum(nil))
+
+	u.head = make([]byte, 512)
+	n, _ := file.ReadAt(u.head, 0)
+	u.head = u.head[:n]
+	return u, nil
+}
+
+// Close removes the temporary file
+func (u *upload) Close() {
+	u.file.Close()
+	os.Remove(u.file.Name())
+}
+
+// store puts the upload under key unless a blob of the same content is there already
+func (u *upload) store(ctx context.Context, key, contentType string) error {
+	return putBlob(ctx, key, u.file, u.size, contentType)
+}
+
+// putBlob stores content under a content hash key, an existing blob of the size is kept
+func putBlob(ctx context.Context, key string, content io.ReadSeeker, size int64, contentType string) error {
+	store := blob.GetStore()
+	if info, err := store.Stat(ctx, key); err == nil && info.Size == size {
+		return nil
+	} else if err != nil && !errors.Is(err, blob.ErrNotFound) {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return store.Put(ctx, key, content, size, contentType)
+}
+
+// lockBlob serializes releasing a blob and linking a row to it until the transaction ends
+func lockBlob(tx *gorm.DB, key string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
+
+// releaseBlob deletes a blob that no row of model refers to anymore. An upload of the same content
+// checks the blob again under the lock before it links a row, see UploadAudio
+func releaseBlob(ctx context.Context, db *gorm.DB, model interface{}, key string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := lockBlob(tx, key); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(model).Where("key = ?", key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		return blob.GetStore().Delete(ctx, key)
+	})
+}
+
+// contentKey is the blob key of content with the hash, files are spread over 256 directories
+func contentKey(prefix, sha string) string {
+	return prefix + "/" + sha[:2] + "/" + sha
+}
@@ -0,0 +1,65 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"music_library_api/internal/releasedate"
+	"net/http"
+	"net/url"
+)
+
+// APIProvider asks the external swagger API: GET {API_URL}/info?group=..&song=..
+type APIProvider struct {
+	BaseURL string
+	Client  *Client
+}
+
+func NewAPIProvider(baseURL string, client *Client) *APIProvider {
+	return &APIProvider{BaseURL: baseURL, Client: client}
+}
+
+func (p *APIProvider) Name() string {
+	return "api"
+}
+
+func (p *APIProvider) Fetch(ctx context.Context, group, song string) (*SongInfo, error) {
+	apiURL := fmt.Sprintf("%s/info?group=%s&song=%s", p.BaseURL,
+		url.QueryEscape(group), url.QueryEscape(song)) // url format
+
+	resp, err := p.Client.Get(ctx, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch song info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("song info api returned status %d", resp.StatusCode)
+	}
+
+	//struct for answer swagger api
+	var songInfo struct {
+		ReleaseDate string `json:"releaseDate"`
+		Text        string `json:"text"`
+		Link        string `json:"link"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&songInfo); err != nil {
+		return nil, fmt.Errorf("invalid api response: %w", err)
+	}
+
+	parsedDate, precision, err := releasedate.Parse(songInfo.ReleaseDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format from api: %w", err)
+	}
+
+	return &SongInfo{
+		ReleaseDate: parsedDate,
+		Precision:   precision,
+		Text:        songInfo.Text,
+		Link:        songInfo.Link,
+		Source:      p.Name(),
+	}, nil
+}
@@ -0,0 +1,221 @@
+package enrichment
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CacheConfig sets TTLs and size of the enrichment cache
+type CacheConfig struct {
+	TTL         time.Duration // found songs
+	NegativeTTL time.Duration // not found songs
+	Size        int           // max entries in memory
+	Persist     bool          // keep entries in Postgres too
+}
+
+// CacheConfigFromEnv reads ENRICHMENT_CACHE_* variables, TTL 0 disables the cache
+func CacheConfigFromEnv() CacheConfig {
+	cfg := CacheConfig{TTL: 24 * time.Hour, NegativeTTL: time.Hour, Size: 1000}
+	if v, ok := os.LookupEnv("ENRICHMENT_CACHE_TTL"); ok {
+		cfg.TTL, _ = time.ParseDuration(v)
+	}
+	if d, err := time.ParseDuration(os.Getenv("ENRICHMENT_CACHE_NEGATIVE_TTL")); err == nil {
+		cfg.NegativeTTL = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("ENRICHMENT_CACHE_SIZE")); err == nil && n > 0 {
+		cfg.Size = n
+	}
+	cfg.Persist, _ = strconv.ParseBool(os.Getenv("ENRICHMENT_CACHE_PERSIST"))
+	return cfg
+}
+
+// CacheEntry is one cached answer, nil Info means upstream said not found
+type CacheEntry struct {
+	Key       string    `json:"key"`
+	Group     string    `json:"group"`
+	Song      string    `json:"song"`
+	Info      *SongInfo `json:"info"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CacheStore persists entries between restarts
+type CacheStore interface {
+	Get(key string) (*CacheEntry, error) // nil, nil when missing
+	Set(entry CacheEntry) error
+	Delete(key string) error
+	DeleteAll() error
+	List(limit int) ([]CacheEntry, error)
+}
+
+type bypassKey struct{}
+
+// WithoutCache makes Fetch skip cached answers and ask upstream, the fresh answer is still stored
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// CachedProvider caches answers of another provider in an LRU and optional store
+type CachedProvider struct {
+	next  MetadataProvider
+	cfg   CacheConfig
+	store CacheStore // may be nil
+
+	mu    sync.Mutex
+	order *list.List // front - most recently used
+	items map[string]*list.Element
+}
+
+func NewCachedProvider(next MetadataProvider, cfg CacheConfig, store CacheStore) *CachedProvider {
+	return &CachedProvider{
+		next:  next,
+		cfg:   cfg,
+		store: store,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (p *CachedProvider) Name() string {
+	return "cached(" + p.next.Name() + ")"
+}
+
+func (p *CachedProvider) Fetch(ctx context.Context, group, song string) (*SongInfo, error) {
+	key := normalizeKey(group, song)
+
+	if bypass, _ := ctx.Value(bypassKey{}).(bool); !bypass {
+		if entry := p.lookup(key); entry != nil {
+			if entry.Info == nil {
+				return nil, ErrNotFound
+			}
+			info := *entry.Info
+			return &info, nil
+		}
+	}
+
+	info, err := p.next.Fetch(ctx, group, song)
+	switch {
+	case err == nil:
+		p.put(CacheEntry{Key: key, Group: group, Song: song, Info: info}, p.cfg.TTL)
+	case errors.Is(err, ErrNotFound):
+		p.put(CacheEntry{Key: key, Group: group, Song: song}, p.cfg.NegativeTTL)
+	}
+	return info, err
+}
+
+// lookup checks memory first, then the store
+func (p *CachedProvider) lookup(key string) *CacheEntry {
+	now := time.Now()
+
+	p.mu.Lock()
+	if el, ok := p.items[key]; ok {
+		entry := el.Value.(*CacheEntry)
+		if now.Before(entry.ExpiresAt) {
+			p.order.MoveToFront(el)
+			p.mu.Unlock()
+			return entry
+		}
+		p.removeLocked(el)
+	}
+	p.mu.Unlock()
+
+	if p.store == nil {
+		return nil
+	}
+	entry, err := p.store.Get(key)
+	if err != nil {
+		log.Printf("Error: Enrichment cache store read failed: %v", err)
+		return nil
+	}
+	if entry == nil || !now.Before(entry.ExpiresAt) {
+		return nil
+	}
+	p.putMemory(*entry)
+	return entry
+}
+
+func (p *CachedProvider) put(entry CacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	entry.CreatedAt = time.Now()
+	entry.ExpiresAt = entry.CreatedAt.Add(ttl)
+	p.putMemory(entry)
+	if p.store != nil {
+		if err := p.store.Set(entry); err != nil {
+			log.Printf("Error: Enrichment cache store write failed: %v", err)
+		}
+	}
+}
+
+func (p *CachedProvider) putMemory(entry CacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if el, ok := p.items[entry.Key]; ok {
+		el.Value = &entry
+		p.order.MoveToFront(el)
+		return
+	}
+	p.items[entry.Key] = p.order.PushFront(&entry)
+	for p.order.Len() > p.cfg.Size {
+		p.removeLocked(p.order.Back())
+	}
+}
+
+func (p *CachedProvider) removeLocked(el *list.Element) {
+	p.order.Remove(el)
+	delete(p.items, el.Value.(*CacheEntry).Key)
+}
+
+// Entries returns memory entries from most recently used, and stored ones if any
+func (p *CachedProvider) Entries(limit int) (memory []CacheEntry, stored []CacheEntry, err error) {
+	p.mu.Lock()
+	for el := p.order.Front(); el != nil && len(memory) < limit; el = el.Next() {
+		memory = append(memory, *el.Value.(*CacheEntry))
+	}
+	p.mu.Unlock()
+
+	if p.store != nil {
+		stored, err = p.store.List(limit)
+	}
+	return memory, stored, err
+}
+
+// Lookup returns the cached entry of a song without calling upstream
+func (p *CachedProvider) Lookup(group, song string) *CacheEntry {
+	return p.lookup(normalizeKey(group, song))
+}
+
+// Invalidate drops a song from memory and store
+func (p *CachedProvider) Invalidate(group, song string) error {
+	key := normalizeKey(group, song)
+	p.mu.Lock()
+	if el, ok := p.items[key]; ok {
+		p.removeLocked(el)
+	}
+	p.mu.Unlock()
+
+	if p.store != nil {
+		return p.store.Delete(key)
+	}
+	return nil
+}
+
+// InvalidateAll clears the whole cache
+func (p *CachedProvider) InvalidateAll() error {
+	p.mu.Lock()
+	p.order.Init()
+	p.items = make(map[string]*list.Element)
+	p.mu.Unlock()
+
+	if p.store != nil {
+		return p.store.DeleteAll()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package enrichment
+
+import (
+	"errors"
+	"log"
+	"music_library_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const cacheCleanupPeriod = time.Hour
+
+// PGCacheStore keeps cache entries in the enrichment_cache_entries table
+type PGCacheStore struct {
+	db *gorm.DB
+}
+
+// NewPGCacheStore also starts deleting expired entries, they are never read again
+func NewPGCacheStore(db *gorm.DB) *PGCacheStore {
+	s := &PGCacheStore{db: db}
+	go s.cleanup()
+	return s
+}
+
+// cleanup deletes expired entries
+func (s *PGCacheStore) cleanup() {
+	ticker := time.NewTicker(cacheCleanupPeriod)
+	defer ticker.Stop()
+	for range ticker.C {
+		res := s.db.Where("expires_at < ?", time.Now()).Delete(&models.EnrichmentCacheEntry{})
+		if res.Error != nil {
+			log.Printf("Error: Failed to delete expired enrichment cache entries: %v", res.Error)
+			continue
+		}
+		if res.RowsAffected > 0 {
+			log.Printf("Info: Deleted %d expired enrichment cache entries", res.RowsAffected)
+		}
+	}
+}
+
+func (s *PGCacheStore) Get(key string) (*CacheEntry, error) {
+	var row models.EnrichmentCacheEntry
+	err := s.db.Where("key = ?", key).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := fromRow(row)
+	return &entry, nil
+}
+
+func (s *PGCacheStore) Set(entry CacheEntry) error {
+	row := models.EnrichmentCacheEntry{
+		Key:       entry.Key,
+		Group:     entry.Group,
+		Song:      entry.Song,
+		Found:     entry.Info != nil,
+		ExpiresAt: entry.ExpiresAt,
+		CreatedAt: entry.CreatedAt,
+	}
+	if entry.Info != nil {
+		row.ReleaseDate = entry.Info.ReleaseDate
+		row.Precision = entry.Info.Precision
+		row.Text = entry.Info.Text
+		row.Link = entry.Info.Link
+		row.Source = entry.Info.Source
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+}
+
+func (s *PGCacheStore) Delete(key string) error {
+	return s.db.Where("key = ?", key).Delete(&models.EnrichmentCacheEntry{}).Error
+}
+
+func (s *PGCacheStore) DeleteAll() error {
+	return s.db.Where("1 = 1").Delete(&models.EnrichmentCacheEntry{}).Error
+}
+
+// List returns not expired entries, newest first
+func (s *PGCacheStore) List(limit int) ([]CacheEntry, error) {
+	var rows []models.EnrichmentCacheEntry
+	err := s.db.Where("expires_at > ?", time.Now()).Order("created_at DESC").Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]CacheEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = fromRow(row)
+	}
+	return entries, nil
+}
+
+func fromRow(row models.EnrichmentCacheEntry) CacheEntry {
+	entry := CacheEntry{
+		Key:       row.Key,
+		Group:     row.Group,
+		Song:      row.Song,
+		ExpiresAt: row.ExpiresAt,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.Found {
+		entry.Info = &SongInfo{
+			ReleaseDate: row.ReleaseDate,
+			Precision:   row.Precision,
+			Text:        row.Text,
+			Link:        row.Link,
+			Source:      row.Source,
+		}
+	}
+	return entry
+}
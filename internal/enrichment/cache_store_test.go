@@ -0,0 +1,88 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"music_library_api/internal/models"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	key := normalizeKey("  Muse ", "Supermassive   BLACK Hole")
+	if key != "muse|supermassive black hole" {
+		t.Fatalf("key = %q", key)
+	}
+	if strings.ContainsRune(key, 0) {
+		t.Fatalf("key %q contains NUL, Postgres text rejects it", key)
+	}
+}
+
+func TestCacheEntryJSON(t *testing.T) {
+	entry := CacheEntry{
+		Key:  normalizeKey("Muse", "Uprising"),
+		Info: &SongInfo{ReleaseDate: time.Date(2009, 9, 7, 0, 0, 0, 0, time.UTC), Precision: "day", Text: "t", Link: "l", Source: "file"},
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := got["info"].(map[string]interface{})
+	for _, name := range []string{"releaseDate", "releaseDatePrecision", "text", "link", "source"} {
+		if _, ok := info[name]; !ok {
+			t.Errorf("info has no %q field: %s", name, data)
+		}
+	}
+}
+
+// TestPGCacheStore needs a disposable database, e.g.
+// TEST_DATABASE_DSN="host=localhost user=postgres dbname=music_test sslmode=disable"
+func TestPGCacheStore(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.EnrichmentCacheEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	store := &PGCacheStore{db: db}
+	key := normalizeKey("Muse", "Uprising")
+	t.Cleanup(func() { store.Delete(key) })
+
+	entry := CacheEntry{
+		Key:       key,
+		Group:     "Muse",
+		Song:      "Uprising",
+		Info:      &SongInfo{ReleaseDate: time.Date(2009, 9, 7, 0, 0, 0, 0, time.UTC), Precision: "day", Source: "file"},
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := store.Set(entry); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got == nil || got.Info == nil || got.Info.Precision != "day" || !got.Info.ReleaseDate.Equal(entry.Info.ReleaseDate) {
+		t.Fatalf("Get = %+v", got)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, err := store.Get(key); err != nil || got != nil {
+		t.Fatalf("Get after Delete = %+v, %v", got, err)
+	}
+}
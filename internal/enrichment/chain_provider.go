@@ -0,0 +1,48 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+)
+
+// ChainProvider tries providers in order and returns the first found info
+type ChainProvider struct {
+	providers []MetadataProvider
+}
+
+func NewChainProvider(providers ...MetadataProvider) *ChainProvider {
+	return &ChainProvider{providers: providers}
+}
+
+func (p *ChainProvider) Name() string {
+	names := make([]string, len(p.providers))
+	for i, provider := range p.providers {
+		names[i] = provider.Name()
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+// Fetch returns ErrNotFound only if every provider said not found,
+// otherwise the last real error is returned
+func (p *ChainProvider) Fetch(ctx context.Context, group, song string) (*SongInfo, error) {
+	var lastErr error
+	for _, provider := range p.providers {
+		info, err := provider.Fetch(ctx, group, song)
+		if err == nil {
+			return info, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Error: Metadata provider %s failed: %v", provider.Name(), err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,243 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling upstream while it is considered down
+var ErrCircuitOpen = errors.New("upstream is unavailable, circuit breaker is open")
+
+// StatusError is an upstream 5xx left after all retries
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned status %d", e.Code)
+}
+
+// ClientConfig tunes the outbound client
+type ClientConfig struct {
+	Timeout          time.Duration // per attempt
+	MaxRetries       int           // attempts after the first one
+	BaseBackoff      time.Duration // doubled on every retry
+	MaxBackoff       time.Duration
+	BreakerThreshold int           // failed calls in a row that open the breaker
+	BreakerCooldown  time.Duration // open time before a trial call
+}
+
+// ConfigFromEnv reads ENRICHMENT_* variables, falling back to defaults
+func ConfigFromEnv() ClientConfig {
+	cfg := ClientConfig{
+		Timeout:          5 * time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      200 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+	if d, err := time.ParseDuration(os.Getenv("ENRICHMENT_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("ENRICHMENT_RETRIES")); err == nil && n >= 0 {
+		cfg.MaxRetries = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("ENRICHMENT_BREAKER_THRESHOLD")); err == nil && n > 0 {
+		cfg.BreakerThreshold = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("ENRICHMENT_BREAKER_COOLDOWN")); err == nil && d > 0 {
+		cfg.BreakerCooldown = d
+	}
+	return cfg
+}
+
+// Client is an HTTP client with timeouts, retries and a circuit breaker
+type Client struct {
+	http    *http.Client
+	cfg     ClientConfig
+	breaker *breaker
+	metrics *Metrics
+}
+
+func NewClient(cfg ClientConfig) *Client {
+	return &Client{
+		http:    &http.Client{},
+		cfg:     cfg,
+		breaker: &breaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown},
+		metrics: newMetrics(),
+	}
+}
+
+// Get sends GET with retries. 4xx responses are returned as is,
+// the caller must close the body.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	if !c.breaker.allow() {
+		c.metrics.shortCircuit()
+		return nil, ErrCircuitOpen
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, url)
+		if err == nil && resp.StatusCode < 500 {
+			c.breaker.success()
+			return resp, nil
+		}
+		if ctx.Err() != nil { // caller gave up, not upstream's fault
+			c.breaker.abort()
+			return nil, ctx.Err()
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = &StatusError{Code: resp.StatusCode}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if attempt >= c.cfg.MaxRetries {
+			c.breaker.failure()
+			return nil, lastErr
+		}
+
+		backoff := c.backoff(attempt)
+		log.Printf("Info: Upstream call failed (%v), retry %d in %s", lastErr, attempt+1, backoff)
+		c.metrics.retry()
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			c.breaker.abort()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt is one request limited by the per-attempt timeout
+func (c *Client) attempt(ctx context.Context, url string) (*http.Response, error) {
+	actx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	req, err := http.NewRequestWithContext(actx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	c.metrics.observe(time.Since(start), err != nil || resp.StatusCode >= 500)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel} // timeout covers body reading too
+	return resp, nil
+}
+
+// backoff is exponential with full jitter: random in [0, min(max, base*2^attempt)]
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.BaseBackoff << attempt
+	if d > c.cfg.MaxBackoff || d <= 0 {
+		d = c.cfg.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// Metrics returns current upstream stats
+func (c *Client) Metrics() MetricsSnapshot {
+	s := c.metrics.snapshot()
+	s.BreakerState = c.breaker.state()
+	return s
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// breaker states
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// breaker opens after threshold failed calls in a row and lets one trial call through after cooldown
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool // trial call in flight
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Since(b.openedAt) < b.cooldown || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= b.threshold {
+		log.Println("Info: Upstream is back, circuit breaker closed")
+	}
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			log.Printf("Error: Upstream failed %d times in a row, circuit breaker opened", b.failures)
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+// abort frees the trial slot when the call was canceled by the caller
+func (b *breaker) abort() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+func (b *breaker) state() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.failures < b.threshold:
+		return breakerClosed
+	case b.trial || time.Since(b.openedAt) >= b.cooldown:
+		return breakerHalfOpen
+	}
+	return breakerOpen
+}
@@ -0,0 +1,70 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"music_library_api/internal/releasedate"
+	"os"
+)
+
+// FileEntry is one song in the metadata file
+type FileEntry struct {
+	Group       string `json:"group"`
+	Song        string `json:"song"`
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// FileProvider serves song info from a local JSON array of FileEntry, loaded once
+type FileProvider struct {
+	songs map[string]SongInfo
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("METADATA_FILE is not set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []FileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+	return NewFileProviderFromEntries(entries)
+}
+
+// NewFileProviderFromEntries builds the provider from already loaded entries
+func NewFileProviderFromEntries(entries []FileEntry) (*FileProvider, error) {
+	p := &FileProvider{songs: make(map[string]SongInfo, len(entries))}
+	for i, e := range entries {
+		date, precision, err := releasedate.Parse(e.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d (%s - %s): invalid releaseDate: %w", i, e.Group, e.Song, err)
+		}
+		p.songs[normalizeKey(e.Group, e.Song)] = SongInfo{
+			ReleaseDate: date,
+			Precision:   precision,
+			Text:        e.Text,
+			Link:        e.Link,
+			Source:      p.Name(),
+		}
+	}
+	return p, nil
+}
+
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+func (p *FileProvider) Fetch(ctx context.Context, group, song string) (*SongInfo, error) {
+	info, ok := p.songs[normalizeKey(group, song)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &info, nil
+}
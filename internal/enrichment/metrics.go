@@ -0,0 +1,99 @@
+package enrichment
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyBuckets upper bounds of the latency histogram
+var latencyBuckets = []time.Duration{
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// Metrics counts upstream requests, one per attempt
+type Metrics struct {
+	mu             sync.Mutex
+	requests       int64
+	errors         int64
+	retries        int64
+	shortCircuited int64
+	totalLatency   time.Duration
+	maxLatency     time.Duration
+	buckets        []int64 // len(latencyBuckets)+1, the last one is +Inf
+}
+
+// MetricsSnapshot is a copy of Metrics for output
+type MetricsSnapshot struct {
+	Requests       int64            `json:"requests"`
+	Errors         int64            `json:"errors"`
+	ErrorRate      float64          `json:"errorRate"`
+	Retries        int64            `json:"retries"`
+	ShortCircuited int64            `json:"shortCircuited"` // calls rejected by the open breaker
+	AvgLatencyMs   float64          `json:"avgLatencyMs"`
+	MaxLatencyMs   float64          `json:"maxLatencyMs"`
+	Latency        map[string]int64 `json:"latencyHistogram"` // "<=100ms": count
+	BreakerState   string           `json:"breakerState"`
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{buckets: make([]int64, len(latencyBuckets)+1)}
+}
+
+func (m *Metrics) observe(latency time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	if failed {
+		m.errors++
+	}
+	m.totalLatency += latency
+	if latency > m.maxLatency {
+		m.maxLatency = latency
+	}
+	i := 0
+	for i < len(latencyBuckets) && latency > latencyBuckets[i] {
+		i++
+	}
+	m.buckets[i]++
+}
+
+func (m *Metrics) retry() {
+	m.mu.Lock()
+	m.retries++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) shortCircuit() {
+	m.mu.Lock()
+	m.shortCircuited++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := MetricsSnapshot{
+		Requests:       m.requests,
+		Errors:         m.errors,
+		Retries:        m.retries,
+		ShortCircuited: m.shortCircuited,
+		MaxLatencyMs:   float64(m.maxLatency) / float64(time.Millisecond),
+		Latency:        make(map[string]int64, len(latencyBuckets)+1),
+	}
+	if m.requests > 0 {
+		s.ErrorRate = float64(m.errors) / float64(m.requests)
+		s.AvgLatencyMs = float64(m.totalLatency) / float64(m.requests) / float64(time.Millisecond)
+	}
+	for i, b := range latencyBuckets {
+		s.Latency["<="+b.String()] = m.buckets[i]
+	}
+	s.Latency[">"+latencyBuckets[len(latencyBuckets)-1].String()] = m.buckets[len(latencyBuckets)]
+	return s
+}
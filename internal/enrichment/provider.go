@@ -0,0 +1,119 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a provider has no info about the song
+var ErrNotFound = errors.New("song info not found")
+
+// SongInfo is the data used to enrich a new song
+type SongInfo struct {
+	ReleaseDate time.Time `json:"releaseDate"`
+	Precision   string    `json:"releaseDatePrecision"` // of ReleaseDate: year, month or day
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+	Source      string    `json:"source"` // provider name
+}
+
+// MetadataProvider looks up song info by group and song name
+type MetadataProvider interface {
+	Name() string
+	Fetch(ctx context.Context, group, song string) (*SongInfo, error)
+}
+
+var (
+	provider MetadataProvider
+	upstream *Client         // shared by api providers
+	cache    *CachedProvider // nil when caching is disabled
+)
+
+// Setup builds the provider from .env:
+// METADATA_PROVIDERS - comma separated list of api and file, tried in order (default api)
+// METADATA_FILE - JSON file for the file provider
+// ENRICHMENT_CACHE_* - answer cache, see CacheConfigFromEnv
+func Setup(db *gorm.DB) {
+	upstream = NewClient(ConfigFromEnv())
+
+	names := os.Getenv("METADATA_PROVIDERS")
+	if names == "" {
+		names = "api"
+	}
+
+	p, err := NewFromConfig(strings.Split(names, ","))
+	if err != nil {
+		log.Fatal("Failed to setup metadata provider:", err)
+	}
+	provider = p
+
+	if cfg := CacheConfigFromEnv(); cfg.TTL > 0 {
+		var store CacheStore
+		if cfg.Persist {
+			store = NewPGCacheStore(db)
+		}
+		cache = NewCachedProvider(provider, cfg, store)
+		provider = cache
+	}
+	log.Printf("Info: Metadata provider: %s", provider.Name())
+}
+
+// GetProvider returns the configured provider
+func GetProvider() MetadataProvider {
+	return provider
+}
+
+// GetCache returns the answer cache, nil when disabled
+func GetCache() *CachedProvider {
+	return cache
+}
+
+// GetMetrics returns stats of calls to the external API
+func GetMetrics() MetricsSnapshot {
+	return upstream.Metrics()
+}
+
+// NewFromConfig builds a provider from names, several names make a chain
+func NewFromConfig(names []string) (MetadataProvider, error) {
+	var providers []MetadataProvider
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "api":
+			if upstream == nil {
+				upstream = NewClient(ConfigFromEnv())
+			}
+			providers = append(providers, NewAPIProvider(os.Getenv("API_URL"), upstream))
+		case "file":
+			p, err := NewFileProvider(os.Getenv("METADATA_FILE"))
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, p)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown metadata provider %q", name)
+		}
+	}
+
+	switch len(providers) {
+	case 0:
+		return nil, errors.New("no metadata providers configured")
+	case 1:
+		return providers[0], nil
+	}
+	return NewChainProvider(providers...), nil
+}
+
+// normalizeKey makes lookups case and space insensitive, the key is the primary key of
+// persisted cache entries, so the separator must be valid in Postgres text (no NUL)
+func normalizeKey(group, song string) string {
+	return strings.ToLower(strings.Join(strings.Fields(group), " ")) + "|" +
+		strings.ToLower(strings.Join(strings.Fields(song), " "))
+}
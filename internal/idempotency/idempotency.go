@@ -0,0 +1,373 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"log"
+	"music_library_api/internal/models"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	Header       = "Idempotency-Key"
+	replayHeader = "Idempotent-Replayed" // set on replayed responses
+	userIDHeader = "X-User-Id"
+)
+
+const (
+	defaultTTL     = 24 * time.Hour
+	maxKeyLength   = 255
+	maxStoredBody  = 1 << 20 // bigger responses are not stored, such requests are not deduplicated
+	cleanupPeriod  = time.Hour
+	inProgressWait = "1"            // Retry-After seconds for a request still in progress
+	lease          = time.Minute    // renewed while the request runs, a retry takes over a key whose lease ran out
+	maxMemoryBody  = 1 << 20        // larger request bodies are hashed into a temporary file
+	maxHashedBody  = int64(1) << 30 // requests with a key are refused above this
+)
+
+var errBodyTooLarge = errors.New("request body is too large")
+
+// Middleware replays the stored response of POST, PUT, PATCH and DELETE requests
+// that repeat an Idempotency-Key of the same caller within ttl
+func Middleware(db *gorm.DB, ttl time.Duration) func(http.Handler) http.Handler {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	go cleanup(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || !writeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				log.Printf("Error: Idempotency key is longer than %d", maxKeyLength)
+				http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+				return
+			}
+			handle(db, ttl, next, w, r, caller(r), key)
+		})
+	}
+}
+
+func writeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// caller scopes keys: the user if known, the client address otherwise
+func caller(r *http.Request) string {
+	if user := r.Header.Get(userIDHeader); user != "" {
+		return "user:" + user
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// newHash starts the request fingerprint, the body is added by hashBody
+func newHash(r *http.Request) hash.Hash {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	return h
+}
+
+// hashBody reads the whole body before the handler runs and gives it back to the request:
+// small bodies are kept in memory, larger ones in a temporary file removed by release
+func hashBody(r *http.Request) (sum string, release func(), err error) {
+	h := newHash(r)
+	release = func() {}
+	if r.Body == nil || r.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), release, nil
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, maxMemoryBody+1))
+	if err != nil {
+		return "", release, err
+	}
+	h.Write(head)
+	if len(head) <= maxMemoryBody {
+		r.Body = readCloser{bytes.NewReader(head), r.Body}
+		return hex.EncodeToString(h.Sum(nil)), release, nil
+	}
+
+	f, err := os.CreateTemp("", "idempotency-*")
+	if err != nil {
+		return "", release, err
+	}
+	release = func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(r.Body, maxHashedBody-int64(len(head))+1))
+	if err == nil && int64(len(head))+n > maxHashedBody {
+		err = errBodyTooLarge
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		release()
+		return "", func() {}, err
+	}
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(head), f), r.Body}
+	return hex.EncodeToString(h.Sum(nil)), release, nil
+}
+
+func handle(db *gorm.DB, ttl time.Duration, next http.Handler, w http.ResponseWriter, r *http.Request, who, key string) {
+	requestHash, release, err := hashBody(r)
+	defer release()
+	if errors.Is(err, errBodyTooLarge) {
+		log.Printf("Error: Request body with idempotency key %q is too large", key)
+		http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		log.Printf("Error: Failed to read request body: %v", err)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	// the stored key may expire or be dropped between the lookups, that is rare
+	for attempt := 0; attempt < 3; attempt++ {
+		record, owned, err := acquire(db, ttl, who, key, requestHash)
+		if err != nil {
+			log.Printf("Error: Failed to store idempotency key: %v", err)
+			http.Error(w, "Error saving data to DB: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if owned {
+			run(db, next, w, r, record)
+			return
+		}
+		if record != nil {
+			replay(w, record, requestHash)
+			return
+		}
+	}
+	log.Printf("Error: Idempotency key %q keeps changing", key)
+	w.Header().Set("Retry-After", inProgressWait)
+	http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+}
+
+// acquire stores a new key, or takes over an expired key or one whose request stopped renewing its lease.
+// owned tells the caller to run the request; otherwise record is the stored one, nil if it vanished meanwhile
+func acquire(db *gorm.DB, ttl time.Duration, who, key, requestHash string) (record *models.IdempotencyRecord, owned bool, err error) {
+	now := time.Now()
+	fresh := models.IdempotencyRecord{
+		Caller:      who,
+		Key:         key,
+		RequestHash: requestHash,
+		State:       models.IdempotencyProcessing,
+		LockedUntil: now.Add(lease),
+		ExpiresAt:   now.Add(ttl),
+	}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return &fresh, true, nil
+	}
+
+	var stored models.IdempotencyRecord
+	err = db.Where("caller = ? AND key = ?", who, key).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) { // finished with a server error meanwhile
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	expired := now.After(stored.ExpiresAt)
+	abandoned := stored.State == models.IdempotencyProcessing && now.After(stored.LockedUntil)
+	if !expired && !abandoned {
+		return &stored, false, nil
+	}
+
+	// guarded by the values read, of several retries only one takes the key over
+	guard := db.Model(&models.IdempotencyRecord{}).
+		Where("id = ? AND state = ? AND expires_at = ?", stored.ID, stored.State, stored.ExpiresAt)
+	if stored.LockedUntil.IsZero() { // stored before leases
+		guard = guard.Where("locked_until IS NULL")
+	} else {
+		guard = guard.Where("locked_until = ?", stored.LockedUntil)
+	}
+	res = guard.Updates(map[string]interface{}{
+		"request_hash": requestHash,
+		"state":        models.IdempotencyProcessing,
+		"status":       0,
+		"headers":      "",
+		"body":         nil,
+		"locked_until": fresh.LockedUntil,
+		"expires_at":   fresh.ExpiresAt,
+	})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, false, res.Error
+	}
+	if abandoned {
+		log.Printf("Info: Took over idempotency key %q left in progress", key)
+	}
+	fresh.ID = stored.ID
+	fresh.CreatedAt = stored.CreatedAt
+	return &fresh, true, nil
+}
+
+// run serves the first request of a key and keeps its response
+func run(db *gorm.DB, next http.Handler, w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord) {
+	stop := renew(db, record.ID)
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		if p := recover(); p != nil {
+			stop()
+			drop(db, record) // do not block the key until it expires
+			panic(p)
+		}
+	}()
+	next.ServeHTTP(rec, r)
+	stop()
+
+	// server errors and huge responses are not kept, the client may retry them
+	if rec.status >= http.StatusInternalServerError || rec.overflow {
+		drop(db, record)
+		return
+	}
+	headers, _ := json.Marshal(rec.Header())
+	record.State = models.IdempotencyDone
+	record.Status = rec.status
+	record.Headers = string(headers)
+	record.Body = rec.body.Bytes()
+	if err := db.Save(record).Error; err != nil {
+		log.Printf("Error: Failed to store response of idempotency key %q: %v", record.Key, err)
+	}
+}
+
+// renew extends the lease of a running request until stop is called
+func renew(db *gorm.DB, id int) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := db.Model(&models.IdempotencyRecord{}).
+					Where("id = ? AND state = ?", id, models.IdempotencyProcessing).
+					Update("locked_until", time.Now().Add(lease)).Error
+				if err != nil {
+					log.Printf("Error: Failed to renew idempotency lease: %v", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// drop deletes the key of a request that is not kept
+func drop(db *gorm.DB, record *models.IdempotencyRecord) {
+	db.Where("id = ? AND state = ?", record.ID, models.IdempotencyProcessing).Delete(&models.IdempotencyRecord{})
+}
+
+// replay answers a repeated key: in progress, reused with another request or the stored response
+func replay(w http.ResponseWriter, record *models.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		log.Printf("Error: Idempotency key %q reused with a different request", record.Key)
+		http.Error(w, "Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	if record.State != models.IdempotencyDone {
+		log.Printf("Error: Request with idempotency key %q is still in progress", record.Key)
+		w.Header().Set("Retry-After", inProgressWait)
+		http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+		return
+	}
+
+	var headers http.Header
+	json.Unmarshal([]byte(record.Headers), &headers)
+	for name, values := range headers {
+		w.Header()[name] = values
+	}
+	w.Header().Set(replayHeader, "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+	log.Printf("Info: Replayed response of idempotency key %q", record.Key)
+}
+
+// cleanup deletes expired keys
+func cleanup(db *gorm.DB) {
+	ticker := time.NewTicker(cleanupPeriod)
+	defer ticker.Stop()
+	for range ticker.C {
+		res := db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyRecord{})
+		if res.Error != nil {
+			log.Printf("Error: Failed to delete expired idempotency keys: %v", res.Error)
+			continue
+		}
+		if res.RowsAffected > 0 {
+			log.Printf("Info: Deleted %d expired idempotency keys", res.RowsAffected)
+		}
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder passes the response through and keeps a copy
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	wrote    bool
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wrote {
+		rec.status = status
+		rec.wrote = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.wrote = true
+	if !rec.overflow {
+		if rec.body.Len()+len(p) > maxStoredBody {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(p)
+		}
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the original writer
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// row results
+const (
+	StatusCreated = "created"
+	StatusUpdated = "updated"
+	StatusMerged  = "merged"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// OnDuplicateMerge fills only empty fields of the existing song, used when migrating libraries
+const OnDuplicateMerge = "merge"
+
+// SourceImport marks fields that came from an imported file
+const SourceImport = ingest.SourceImport
+
+const defaultBatchSize = 500
+
+// errDryRun rolls back a batch of a dry run
+var errDryRun = errors.New("dry run")
+
+// Options of an import
+type Options struct {
+	Enrich      bool   // queue enrichment jobs, imported fields are kept like in manual mode
+	OnDuplicate string // ingest.OnDuplicateSkip (default), Update, Error or OnDuplicateMerge
+	DryRun      bool   // validate and write in a transaction that is rolled back
+	BatchSize   int    // rows per transaction
+}
+
+// RowResult is the outcome of one row, Row starts from 1
+type RowResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`         // created, updated, merged, skipped, failed
+	Song   string `json:"song,omitempty"` // "group - song" of a valid row
+	SongID int    `json:"songId,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report of the whole import
+type Report struct {
+	DryRun  bool        `json:"dryRun"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Merged  int         `json:"merged"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []RowResult `json:"rows"`
+}
+
+type pending struct {
+	row  int
+	song models.Song
+}
+
+// Importer writes songs in batches and collects the report
+type Importer struct {
+	db    *gorm.DB
+	opts  Options
+	batch []pending
+	seen  map[string]int // dedup key - first row with it
+	// SongIDs maps rows to the stored song, also for skipped duplicates
+	SongIDs map[int]int
+	report  Report
+	err     error // first database error, later rows are not written
+}
+
+func New(db *gorm.DB, opts Options) *Importer {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = ingest.OnDuplicateSkip
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	return &Importer{
+		db:      db,
+		opts:    opts,
+		seen:    map[string]int{},
+		SongIDs: map[int]int{},
+		report:  Report{DryRun: opts.DryRun, Rows: []RowResult{}},
+	}
+}
+
+// Fail records a row that could not be parsed or validated
+func (im *Importer) Fail(row int, reason string) {
+	im.result(RowResult{Row: row, Status: StatusFailed, Reason: reason})
+}
+
+// Skip records a row that is left out on purpose
+func (im *Importer) Skip(row int, reason string) {
+	im.result(RowResult{Row: row, Status: StatusSkipped, Reason: reason})
+}
+
+// Add queues a valid song, the batch is written when full
+func (im *Importer) Add(row int, song models.Song) {
+	if im.err != nil {
+		return
+	}
+	key := dedup.Key(song.Group, song.Song)
+	if first, ok := im.seen[key]; ok {
+		im.result(RowResult{Row: row, Status: StatusSkipped, Song: title(song), Reason: fmt.Sprintf("duplicate of row %d", first)})
+		if id, ok := im.SongIDs[first]; ok {
+			im.SongIDs[row] = id
+		}
+		return
+	}
+	im.seen[key] = row
+
+	im.batch = append(im.batch, pending{row: row, song: song})
+	if len(im.batch) >= im.opts.BatchSize {
+		im.err = im.flush()
+	}
+}
+
+// Finish writes the last batch and returns the report, rows of batches written before an error stay stored
+func (im *Importer) Finish() (*Report, error) {
+	if im.err == nil {
+		im.err = im.flush()
+	}
+	// rejected rows are reported at once, written ones with their batch
+	sort.Slice(im.report.Rows, func(i, j int) bool { return im.report.Rows[i].Row < im.report.Rows[j].Row })
+	return &im.report, im.err
+}
+
+func (im *Importer) result(r RowResult) {
+	im.report.Total++
+	switch r.Status {
+	case StatusCreated:
+		im.report.Created++
+	case StatusUpdated:
+		im.report.Updated++
+	case StatusMerged:
+		im.report.Merged++
+	case StatusSkipped:
+		im.report.Skipped++
+	case StatusFailed:
+		im.report.Failed++
+	}
+	if r.SongID != 0 {
+		im.SongIDs[r.Row] = r.SongID
+	}
+	if im.opts.DryRun {
+		r.SongID = 0 // rolled back
+	}
+	im.report.Rows = append(im.report.Rows, r)
+}
+
+// flush writes the batch in one transaction, a failed row is rolled back to its savepoint
+func (im *Importer) flush() error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+	batch := im.batch
+	im.batch = nil
+
+	var results []RowResult
+	err := im.db.Transaction(func(tx *gorm.DB) error {
+		keys := make([]string, len(batch))
+		for i, p := range batch {
+			keys[i] = dedup.Key(p.song.Group, p.song.Song)
+		}
+		var stored []models.Song
+		if err := tx.Where("dedup_key IN ?", keys).Find(&stored).Error; err != nil {
+			return err
+		}
+		existing := make(map[string]*models.Song, len(stored))
+		for i := range stored {
+			existing[*stored[i].DedupKey] = &stored[i]
+		}
+
+		for i, p := range batch {
+			r := im.write(tx, p, existing[keys[i]])
+			r.Song = title(p.song)
+			results = append(results, r)
+		}
+		if im.opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	for _, r := range results {
+		im.result(r)
+	}
+	return nil
+}
+
+func (im *Importer) write(tx *gorm.DB, p pending, existing *models.Song) RowResult {
+	if existing != nil {
+		switch im.opts.OnDuplicate {
+		case ingest.OnDuplicateError:
+			return RowResult{Row: p.row, Status: StatusFailed, SongID: existing.ID, Reason: fmt.Sprintf("duplicate of song %d", existing.ID)}
+		case ingest.OnDuplicateUpdate:
+			if _, err := ingest.UpdateDuplicate(tx, existing, p.song, true); err != nil {
+				return RowResult{Row: p.row, Status: StatusFailed, SongID: existing.ID, Reason: err.Error()}
+			}
+			return RowResult{Row: p.row, Status: StatusUpdated, SongID: existing.ID}
+		case OnDuplicateMerge:
+			merged, err := mergeBlanks(tx, existing, p.song)
+			if err != nil {
+				return RowResult{Row: p.row, Status: StatusFailed, SongID: existing.ID, Reason: err.Error()}
+			}
+			if merged {
+				return RowResult{Row: p.row, Status: StatusMerged, SongID: existing.ID}
+			}
+			return RowResult{Row: p.row, Status: StatusSkipped, SongID: existing.ID, Reason: fmt.Sprintf("duplicate of song %d, nothing to merge", existing.ID)}
+		}
+		return RowResult{Row: p.row, Status: StatusSkipped, SongID: existing.ID, Reason: fmt.Sprintf("duplicate of song %d", existing.ID)}
+	}
+
+	song := p.song
+	var err error
+	if im.opts.Enrich {
+		_, err = ingest.Enqueue(tx, &song, true)
+	} else {
+		song.Status = models.SongStatusManual
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&song).Error; err != nil {
+				return err
+			}
+			return ingest.SaveProvenance(tx, song.ID, ingest.ClientProvenance(&song, SourceImport))
+		})
+	}
+	if err != nil {
+		return RowResult{Row: p.row, Status: StatusFailed, Reason: err.Error()}
+	}
+	return RowResult{Row: p.row, Status: StatusCreated, SongID: song.ID}
+}
+
+// mergeBlanks copies incoming fields that the existing song lacks, a more precise date of the same period counts as lacking
+func mergeBlanks(tx *gorm.DB, existing *models.Song, incoming models.Song) (bool, error) {
+	provenance := map[string]string{}
+	merged := false
+	if existing.Genre == "" && incoming.Genre != "" {
+		existing.Genre = incoming.Genre
+		merged = true
+	}
+	if existing.Album == "" && incoming.Album != "" {
+		existing.Album = incoming.Album
+		merged = true
+	}
+	if !incoming.ReleaseDate.IsZero() && (existing.ReleaseDate.IsZero() ||
+		releasedate.Refines(existing.ReleaseDate, existing.ReleaseDatePrecision, incoming.ReleaseDate, incoming.ReleaseDatePrecision)) {
+		existing.ReleaseDate = incoming.ReleaseDate
+		existing.ReleaseDatePrecision = incoming.ReleaseDatePrecision
+		provenance[ingest.FieldReleaseDate] = SourceImport
+	}
+	if existing.Text == "" && incoming.Text != "" {
+		existing.Text = incoming.Text
+		provenance[ingest.FieldText] = SourceImport
+	}
+	if existing.Link == "" && incoming.Link != "" {
+		existing.Link = incoming.Link
+		provenance[ingest.FieldLink] = SourceImport
+	}
+	if !merged && len(provenance) == 0 {
+		return false, nil
+	}
+	existing.UpdatedAt = time.Now()
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(existing).Error; err != nil {
+			return err
+		}
+		return ingest.SaveProvenance(tx, existing.ID, provenance)
+	})
+	return err == nil, err
+}
+
+func title(song models.Song) string {
+	return song.Group + " - " + song.Song
+}
@@ -0,0 +1,32 @@
+package ingest
+
+import (
+	"log"
+	"music_library_api/internal/lyrics"
+	"music_library_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReanchorAnnotations moves annotations of a song to the new text or flags them as orphaned
+func ReanchorAnnotations(tx *gorm.DB, song models.Song) error {
+	var annotations []models.Annotation
+	if err := tx.Where("song_id = ?", song.ID).Find(&annotations).Error; err != nil {
+		return err
+	}
+
+	for _, a := range annotations {
+		anchor, ok := lyrics.Reanchor(song.Text, lyrics.Anchor{
+			Verse: a.Verse, Line: a.Line, Start: a.Start, End: a.End, Quote: a.Quote,
+		})
+		a.Orphaned = !ok
+		if ok {
+			a.Verse, a.Line, a.Start, a.End = anchor.Verse, anchor.Line, anchor.Start, anchor.End
+		}
+		if err := tx.Save(&a).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("Debug: Re-anchored %d annotations of song %d", len(annotations), song.ID)
+	return nil
+}
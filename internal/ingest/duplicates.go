@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"errors"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// duplicate handling of AddSong
+const (
+	OnDuplicateError  = "error"  // 409 with the existing song ID
+	OnDuplicateSkip   = "skip"   // return the existing song unchanged
+	OnDuplicateUpdate = "update" // update the existing song from the request
+)
+
+// FindDuplicate returns the stored song with the same dedup key, nil if there is none
+func FindDuplicate(db *gorm.DB, group, song string) (*models.Song, error) {
+	var existing models.Song
+	err := db.Where("dedup_key = ?", dedup.Key(group, song)).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// UpdateDuplicate copies non-empty request fields to the existing song.
+// In manual mode client values are stored with provenance, otherwise the song is queued for re-enrichment.
+func UpdateDuplicate(db *gorm.DB, existing *models.Song, incoming models.Song, manual bool) (*models.EnrichmentJob, error) {
+	if incoming.Genre != "" {
+		existing.Genre = incoming.Genre
+	}
+	if incoming.Album != "" {
+		existing.Album = incoming.Album
+	}
+	oldText := existing.Text
+	provenance := map[string]string{}
+	if manual {
+		provenance = ClientProvenance(&incoming, SourceClient)
+		if !incoming.ReleaseDate.IsZero() {
+			existing.ReleaseDate = incoming.ReleaseDate
+			existing.ReleaseDatePrecision = incoming.ReleaseDatePrecision
+		}
+		if incoming.Text != "" {
+			existing.Text = incoming.Text
+		}
+		if incoming.Link != "" {
+			existing.Link = incoming.Link
+		}
+	}
+	existing.UpdatedAt = time.Now()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(existing).Error; err != nil {
+			return err
+		}
+		// annotations follow the replaced text
+		if existing.Text != oldText {
+			if err := ReanchorAnnotations(tx, *existing); err != nil {
+				return err
+			}
+		}
+		return SaveProvenance(tx, existing.ID, provenance)
+	})
+	if err != nil || manual {
+		return nil, err
+	}
+
+	jobs, err := EnqueueRefresh(db, []int{existing.ID})
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
@@ -0,0 +1,300 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"music_library_api/internal/enrichment"
+	"music_library_api/internal/links"
+	"music_library_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultWorkers     = 4
+	defaultMaxAttempts = 5
+	pollInterval       = 5 * time.Second
+	retryBase          = 10 * time.Second // doubled on every failed attempt
+	jobTimeout         = time.Minute
+)
+
+// wake signals idle workers about a new job
+var wake = make(chan struct{}, 1)
+
+// policy is the configured precedence, used for jobs of manual songs and on refresh for fields of unknown source
+var policy = Policy{Default: PreferClient}
+
+// Enqueue stores a new song as pending together with its enrichment job.
+// manual keeps client fields and merges upstream values by precedence policy.
+func Enqueue(db *gorm.DB, song *models.Song, manual bool) (*models.EnrichmentJob, error) {
+	song.Status = models.SongStatusPending
+	job := &models.EnrichmentJob{Status: models.JobStatusQueued, Manual: manual, NextRunAt: time.Now()}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(song).Error; err != nil {
+			return err
+		}
+		if manual {
+			if err := SaveProvenance(tx, song.ID, ClientProvenance(song, SourceClient)); err != nil {
+				return err
+			}
+		}
+		job.SongID = song.ID
+		return tx.Create(job).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notify()
+	return job, nil
+}
+
+// notify wakes one idle worker
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enrich fetches info for the song and fills its fields.
+// With nil policy upstream overwrites every field, otherwise values are merged.
+// Returns the source of every changed field.
+func Enrich(ctx context.Context, provider enrichment.MetadataProvider, song *models.Song, policy *Policy) (map[string]string, error) {
+	info, err := provider.Fetch(ctx, song.Group, song.Song)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Debug: Song info from %s provider %+v", info.Source, info)
+	return apply(song, info, policy), nil
+}
+
+// apply fills song fields from upstream info, a manual song keeps its status
+func apply(song *models.Song, info *enrichment.SongInfo, policy *Policy) map[string]string {
+	provenance := map[string]string{}
+	if policy != nil {
+		Merge(song, info, *policy, provenance)
+	} else {
+		song.ReleaseDate = info.ReleaseDate
+		song.ReleaseDatePrecision = info.Precision
+		song.Text = info.Text
+		song.Link = info.Link
+		provenance[FieldReleaseDate] = info.Source
+		provenance[FieldText] = info.Source
+		provenance[FieldLink] = info.Source
+	}
+	// the same form Song.BeforeSave stores
+	if link, err := links.Parse(song.Link, ""); err == nil {
+		song.Link = link.URL
+	}
+	now := time.Now()
+	if song.Status != models.SongStatusManual {
+		song.Status = models.SongStatusEnriched
+	}
+	song.EnrichedAt = &now
+	return provenance
+}
+
+// StartWorkers runs the worker pool, jobs left running by a previous process are queued again
+func StartWorkers(db *gorm.DB, workers, maxAttempts int, precedence Policy) {
+	policy = precedence
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	err := db.Model(&models.EnrichmentJob{}).Where("status = ?", models.JobStatusRunning).
+		Update("status", models.JobStatusQueued).Error
+	if err != nil {
+		log.Printf("Error: Failed to requeue interrupted jobs: %v", err)
+	}
+
+	for i := 0; i < workers; i++ {
+		go worker(db, maxAttempts)
+	}
+	log.Printf("Info: Ingest workers started: %d, max attempts %d", workers, maxAttempts)
+}
+
+func worker(db *gorm.DB, maxAttempts int) {
+	for {
+		job, err := claim(db)
+		if err != nil {
+			log.Printf("Error: Failed to claim enrichment job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		process(db, job, maxAttempts)
+	}
+}
+
+// claim takes the oldest due job, SKIP LOCKED lets workers run in parallel
+func claim(db *gorm.DB) (*models.EnrichmentJob, error) {
+	var job models.EnrichmentJob
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", models.JobStatusQueued, time.Now()).
+			Order("next_run_at").First(&job).Error
+		if err != nil {
+			return err
+		}
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		return tx.Save(&job).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func process(db *gorm.DB, job *models.EnrichmentJob, maxAttempts int) {
+	var song models.Song
+	if err := db.First(&song, job.SongID).Error; err != nil {
+		log.Printf("Error: Song %d of job %d not found, job failed", job.SongID, job.ID)
+		job.Status = models.JobStatusFailed
+		job.LastError = "song not found"
+		db.Save(job)
+		return
+	}
+
+	// the scheduler waits maxAge after failed refreshes too
+	if job.Refresh {
+		err := db.Model(&models.Song{}).Where("id = ?", song.ID).UpdateColumn("refresh_attempted_at", time.Now()).Error
+		if err != nil {
+			log.Printf("Error: Failed to mark refresh of song %d: %v", song.ID, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	if job.Refresh { // refresh must see current upstream data
+		ctx = enrichment.WithoutCache(ctx)
+	}
+	info, err := enrichment.GetProvider().Fetch(ctx, song.Group, song.Song)
+	cancel()
+	if err == nil {
+		log.Printf("Debug: Song info from %s provider %+v", info.Source, info)
+	}
+
+	// stored song keeps its data when upstream lost it
+	if errors.Is(err, enrichment.ErrNotFound) && job.Refresh {
+		log.Printf("Error: Refresh job %d: no upstream info for song %d anymore", job.ID, song.ID)
+		job.Status = models.JobStatusFailed
+		job.LastError = err.Error()
+		db.Save(job)
+		return
+	}
+
+	// manual song stays as the client sent it when upstream knows nothing
+	if errors.Is(err, enrichment.ErrNotFound) && job.Manual {
+		log.Printf("Info: No upstream info for manual song %d, keeping client data", song.ID)
+		info, err = nil, nil
+	}
+
+	if err == nil {
+		job.Status = models.JobStatusDone
+		job.LastError = ""
+		var saved models.Song
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if saved, err = store(tx, job, info); err != nil {
+				return err
+			}
+			return tx.Save(job).Error
+		})
+		if err == nil {
+			log.Printf("Info: Song %d processed by job %d, status %s", saved.ID, job.ID, saved.Status)
+			return
+		}
+		log.Printf("Error: Failed to save enriched song %d: %v", song.ID, err)
+	}
+
+	job.LastError = err.Error()
+	// not found will not appear on retry
+	if errors.Is(err, enrichment.ErrNotFound) || job.Attempts >= maxAttempts {
+		log.Printf("Error: Job %d failed after %d attempts: %v", job.ID, job.Attempts, err)
+		job.Status = models.JobStatusFailed
+		if !job.Refresh { // refreshed song keeps its status
+			song.Status = models.SongStatusFailed
+			if job.Manual {
+				song.Status = models.SongStatusManual
+			}
+			db.Model(&song).Update("status", song.Status)
+		}
+	} else {
+		delay := retryBase << (job.Attempts - 1)
+		log.Printf("Info: Job %d attempt %d failed: %v, retry in %s", job.ID, job.Attempts, err, delay)
+		job.Status = models.JobStatusQueued
+		job.NextRunAt = time.Now().Add(delay)
+	}
+	if err := db.Save(job).Error; err != nil {
+		log.Printf("Error: Failed to update job %d: %v", job.ID, err)
+	}
+}
+
+// store applies upstream info to the locked current row of the song and writes only the enriched columns,
+// so edits made while the provider was queried are kept. Nil info keeps client data of a manual song.
+func store(tx *gorm.DB, job *models.EnrichmentJob, info *enrichment.SongInfo) (models.Song, error) {
+	var song models.Song
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&song, job.SongID).Error; err != nil {
+		return song, err
+	}
+	old := song
+
+	provenance := map[string]string{}
+	if info == nil {
+		song.Status = models.SongStatusManual
+	} else {
+		var jobPolicy *Policy
+		switch {
+		case job.Refresh:
+			sources, err := loadProvenance(tx, song.ID)
+			if err != nil {
+				return song, err
+			}
+			p := refreshPolicy(sources)
+			jobPolicy = &p
+		case job.Manual:
+			jobPolicy = &policy
+		}
+		provenance = apply(&song, info, jobPolicy)
+	}
+
+	// without hooks, a stale link is not added back and the dedup key is untouched
+	err := tx.Model(&models.Song{}).Where("id = ?", song.ID).UpdateColumns(map[string]interface{}{
+		"release_date":           song.ReleaseDate,
+		"release_date_precision": song.ReleaseDatePrecision,
+		"text":                   song.Text,
+		"link":                   song.Link,
+		"status":                 song.Status,
+		"enriched_at":            song.EnrichedAt,
+		"updated_at":             time.Now(),
+	}).Error
+	if err != nil {
+		return song, err
+	}
+	if song.Link != old.Link {
+		if err := models.AddLegacyLink(tx, song.ID, song.Link); err != nil {
+			return song, err
+		}
+	}
+	if song.Text != old.Text {
+		if err := ReanchorAnnotations(tx, song); err != nil {
+			return song, err
+		}
+	}
+	return song, SaveProvenance(tx, song.ID, provenance)
+}
@@ -0,0 +1,172 @@
+package ingest
+
+import (
+	"fmt"
+	"music_library_api/internal/enrichment"
+	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// enriched fields
+const (
+	FieldReleaseDate = "releaseDate"
+	FieldText        = "text"
+	FieldLink        = "link"
+)
+
+// sources that are not providers
+const (
+	SourceClient = "client"
+	SourceEdit   = "edit"
+	SourceImport = "import"
+)
+
+// precedence values
+const (
+	PreferClient   = "client"   // upstream only fills blanks
+	PreferUpstream = "upstream" // upstream overrides client values
+)
+
+// Policy is the precedence of client over upstream values per field
+type Policy struct {
+	Default string
+	Fields  map[string]string
+}
+
+// PolicyFromEnv reads ENRICHMENT_PRECEDENCE: "client", "upstream" or
+// per field list like "upstream,text=client" (first item without = is the default)
+func PolicyFromEnv() (Policy, error) {
+	return ParsePolicy(os.Getenv("ENRICHMENT_PRECEDENCE"))
+}
+
+func ParsePolicy(value string) (Policy, error) {
+	p := Policy{Default: PreferClient, Fields: map[string]string{}}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field, prefer, ok := strings.Cut(item, "=")
+		if !ok {
+			prefer, field = field, ""
+		}
+		if prefer != PreferClient && prefer != PreferUpstream {
+			return p, fmt.Errorf("invalid precedence %q, use client or upstream", prefer)
+		}
+		switch field {
+		case "":
+			p.Default = prefer
+		case FieldReleaseDate, FieldText, FieldLink:
+			p.Fields[field] = prefer
+		default:
+			return p, fmt.Errorf("unknown field %q in precedence", field)
+		}
+	}
+	return p, nil
+}
+
+func (p Policy) prefer(field string) string {
+	if v, ok := p.Fields[field]; ok {
+		return v
+	}
+	return p.Default
+}
+
+// Merge fills song fields from upstream info by policy and returns the source of every set field
+func Merge(song *models.Song, info *enrichment.SongInfo, policy Policy, provenance map[string]string) {
+	take := func(field string, clientSet, upstreamSet bool) bool {
+		if !upstreamSet {
+			return false
+		}
+		if !clientSet || policy.prefer(field) == PreferUpstream {
+			provenance[field] = info.Source
+			return true
+		}
+		return false
+	}
+
+	// a partial client date does not block a precise upstream date of the same period
+	clientDate := !song.ReleaseDate.IsZero() &&
+		!releasedate.Refines(song.ReleaseDate, song.ReleaseDatePrecision, info.ReleaseDate, info.Precision)
+	if take(FieldReleaseDate, clientDate, !info.ReleaseDate.IsZero()) {
+		song.ReleaseDate = info.ReleaseDate
+		song.ReleaseDatePrecision = info.Precision
+	}
+	if take(FieldText, song.Text != "", info.Text != "") {
+		song.Text = info.Text
+	}
+	if take(FieldLink, song.Link != "", info.Link != "") {
+		song.Link = info.Link
+	}
+}
+
+// userSource reports whether a field value was set by a user, not fetched from a provider
+func userSource(source string) bool {
+	return source == SourceClient || source == SourceEdit || source == SourceImport
+}
+
+// refreshPolicy of a stored song: values set by users are kept, values of providers are updated
+// and fields of unknown source follow the configured precedence
+func refreshPolicy(sources map[string]string) Policy {
+	p := Policy{Default: policy.Default, Fields: map[string]string{}}
+	for _, field := range []string{FieldReleaseDate, FieldText, FieldLink} {
+		source, ok := sources[field]
+		switch {
+		case !ok:
+			p.Fields[field] = policy.prefer(field)
+		case userSource(source):
+			p.Fields[field] = PreferClient
+		default:
+			p.Fields[field] = PreferUpstream
+		}
+	}
+	return p
+}
+
+// loadProvenance returns the source of every field of a song with a known source
+func loadProvenance(db *gorm.DB, songID int) (map[string]string, error) {
+	var records []models.FieldProvenance
+	if err := db.Where("song_id = ?", songID).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string, len(records))
+	for _, r := range records {
+		sources[r.Field] = r.Source
+	}
+	return sources, nil
+}
+
+// ClientProvenance marks non-empty song fields as sent by the client
+func ClientProvenance(song *models.Song, source string) map[string]string {
+	provenance := map[string]string{}
+	if !song.ReleaseDate.IsZero() {
+		provenance[FieldReleaseDate] = source
+	}
+	if song.Text != "" {
+		provenance[FieldText] = source
+	}
+	if song.Link != "" {
+		provenance[FieldLink] = source
+	}
+	return provenance
+}
+
+// SaveProvenance upserts field sources of a song
+func SaveProvenance(tx *gorm.DB, songID int, provenance map[string]string) error {
+	for field, source := range provenance {
+		record := models.FieldProvenance{SongID: songID, Field: field, Source: source}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "song_id"}, {Name: "field"}},
+			DoUpdates: clause.AssignmentColumns([]string{"source", "updated_at"}),
+		}).Create(&record).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
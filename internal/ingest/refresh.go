@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"music_library_api/internal/enrichment"
+	"music_library_api/internal/models"
+	"music_library_api/internal/releasedate"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Change is one field difference between stored and upstream data
+type Change struct {
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Source string `json:"source"`
+}
+
+// Preview fetches upstream info and returns what re-enrichment would change, the song is not modified
+func Preview(ctx context.Context, db *gorm.DB, provider enrichment.MetadataProvider, song models.Song) (map[string]Change, error) {
+	sources, err := loadProvenance(db, song.ID)
+	if err != nil {
+		return nil, err
+	}
+	p := refreshPolicy(sources)
+	updated := song
+	provenance, err := Enrich(enrichment.WithoutCache(ctx), provider, &updated, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	oldDate := releasedate.Format(song.ReleaseDate, song.ReleaseDatePrecision)
+	newDate := releasedate.Format(updated.ReleaseDate, updated.ReleaseDatePrecision)
+	if newDate != oldDate {
+		changes[FieldReleaseDate] = Change{Old: oldDate, New: newDate, Source: provenance[FieldReleaseDate]}
+	}
+	if updated.Text != song.Text {
+		changes[FieldText] = Change{Old: song.Text, New: updated.Text, Source: provenance[FieldText]}
+	}
+	if updated.Link != song.Link {
+		changes[FieldLink] = Change{Old: song.Link, New: updated.Link, Source: provenance[FieldLink]}
+	}
+	return changes, nil
+}
+
+// EnqueueRefresh queues re-enrichment of stored songs, songs with an unfinished job are skipped
+func EnqueueRefresh(db *gorm.DB, songIDs []int) ([]models.EnrichmentJob, error) {
+	if len(songIDs) == 0 {
+		return nil, nil
+	}
+
+	var jobs []models.EnrichmentJob
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var busy []int
+		err := tx.Model(&models.EnrichmentJob{}).
+			Where("song_id IN ? AND status IN ?", songIDs, []string{models.JobStatusQueued, models.JobStatusRunning}).
+			Pluck("song_id", &busy).Error
+		if err != nil {
+			return err
+		}
+		skip := make(map[int]bool, len(busy))
+		for _, id := range busy {
+			skip[id] = true
+		}
+
+		now := time.Now()
+		for _, id := range songIDs {
+			if skip[id] {
+				continue
+			}
+			skip[id] = true
+			jobs = append(jobs, models.EnrichmentJob{SongID: id, Status: models.JobStatusQueued, Refresh: true, NextRunAt: now})
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&jobs, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for range jobs {
+		notify()
+	}
+	return jobs, nil
+}
+
+// StartRefresher queues re-enrichment of songs not refreshed for maxAge, checked every interval
+func StartRefresher(db *gorm.DB, interval, maxAge time.Duration) {
+	if maxAge <= 0 {
+		log.Println("Info: Scheduled re-enrichment is disabled")
+		return
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	log.Printf("Info: Re-enrichment scheduler started, songs older than %s, every %s", maxAge, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			var ids []int
+			err := db.Model(&models.Song{}).
+				Where("status IN ?", []string{models.SongStatusEnriched, models.SongStatusManual}).
+				Where("COALESCE(refresh_attempted_at, enriched_at, created_at) < ?", time.Now().Add(-maxAge)).
+				Pluck("id", &ids).Error
+			if err != nil {
+				log.Printf("Error: Failed to find songs to re-enrich: %v", err)
+				continue
+			}
+			jobs, err := EnqueueRefresh(db, ids)
+			if err != nil {
+				log.Printf("Error: Failed to queue re-enrichment: %v", err)
+				continue
+			}
+			log.Printf("Info: Scheduled re-enrichment of %d songs", len(jobs))
+		}
+	}()
+}
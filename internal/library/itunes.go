@@ -0,0 +1,98 @@
+package library
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ParseITunes reads Library.xml exported by iTunes or Apple Music
+func ParseITunes(r io.Reader) (*Library, error) {
+	root, err := decodePlist(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plist: %w", err)
+	}
+	doc, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid iTunes library: root is not a dict")
+	}
+	tracks, ok := doc["Tracks"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid iTunes library: no Tracks")
+	}
+
+	lib := &Library{Source: SourceITunes}
+	for id, value := range tracks {
+		t, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		track := Track{
+			ID:       id,
+			Artist:   str(t["Artist"]),
+			Name:     str(t["Name"]),
+			Genre:    str(t["Genre"]),
+			Album:    str(t["Album"]),
+			Year:     int(num(t["Year"])),
+			Location: str(t["Location"]),
+		}
+		if track.Artist == "" {
+			track.Artist = str(t["Album Artist"])
+		}
+		if date, ok := t["Release Date"].(time.Time); ok {
+			track.ReleaseDate = date
+		}
+		for _, kind := range []string{"Podcast", "Movie", "TV Show", "Has Video", "Audiobook"} {
+			if flag, _ := t[kind].(bool); flag {
+				track.Skip = "not a song: " + kind
+				break
+			}
+		}
+		lib.Tracks = append(lib.Tracks, track)
+	}
+	// dict order is lost, track IDs keep the library order
+	sort.Slice(lib.Tracks, func(i, j int) bool {
+		a, _ := strconv.Atoi(lib.Tracks[i].ID)
+		b, _ := strconv.Atoi(lib.Tracks[j].ID)
+		return a < b
+	})
+
+	playlists, _ := doc["Playlists"].([]interface{})
+	for _, value := range playlists {
+		p, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// the whole library and built-in lists (Music, Movies, Podcasts) are not user playlists
+		if master, _ := p["Master"].(bool); master {
+			continue
+		}
+		if _, builtin := p["Distinguished Kind"]; builtin {
+			continue
+		}
+		if folder, _ := p["Folder"].(bool); folder {
+			continue
+		}
+		playlist := Playlist{Name: str(p["Name"])}
+		items, _ := p["Playlist Items"].([]interface{})
+		for _, item := range items {
+			if entry, ok := item.(map[string]interface{}); ok {
+				playlist.TrackIDs = append(playlist.TrackIDs, strconv.FormatInt(num(entry["Track ID"]), 10))
+			}
+		}
+		lib.Playlists = append(lib.Playlists, playlist)
+	}
+	return lib, nil
+}
+
+func str(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func num(value interface{}) int64 {
+	n, _ := value.(int64)
+	return n
+}
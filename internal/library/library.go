@@ -0,0 +1,62 @@
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// sources
+const (
+	SourceITunes    = "itunes"
+	SourceRhythmbox = "rhythmbox"
+)
+
+// Track is a song of a desktop library
+type Track struct {
+	ID          string // ID used by playlists of the library
+	Artist      string
+	Name        string
+	Genre       string
+	Album       string
+	Year        int
+	ReleaseDate time.Time // zero if only the year is known
+	Location    string
+	Skip        string // reason to skip a track that is not a song (podcast, video, radio)
+}
+
+// Playlist refers to tracks by Track.ID
+type Playlist struct {
+	Name     string
+	TrackIDs []string
+}
+
+// Library is what was read from the file
+type Library struct {
+	Source    string
+	Tracks    []Track
+	Playlists []Playlist
+}
+
+// Detect tells the source by the beginning of the file
+func Detect(head []byte) (string, error) {
+	switch {
+	case bytes.Contains(head, []byte("<plist")):
+		return SourceITunes, nil
+	case bytes.Contains(head, []byte("<rhythmdb")):
+		return SourceRhythmbox, nil
+	}
+	return "", fmt.Errorf("unknown library file, expected iTunes Library.xml or Rhythmbox rhythmdb.xml")
+}
+
+// Parse reads the library of the source, playlists is Rhythmbox playlists.xml and may be nil
+func Parse(source string, r io.Reader, playlists io.Reader) (*Library, error) {
+	switch source {
+	case SourceITunes:
+		return ParseITunes(r)
+	case SourceRhythmbox:
+		return ParseRhythmbox(r, playlists)
+	}
+	return nil, fmt.Errorf("unknown library source %q", source)
+}
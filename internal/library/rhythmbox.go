@@ -0,0 +1,79 @@
+package library
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type rhythmdb struct {
+	XMLName xml.Name         `xml:"rhythmdb"`
+	Entries []rhythmboxEntry `xml:"entry"`
+}
+
+type rhythmboxEntry struct {
+	Type     string `xml:"type,attr"`
+	Title    string `xml:"title"`
+	Artist   string `xml:"artist"`
+	Genre    string `xml:"genre"`
+	Album    string `xml:"album"`
+	Location string `xml:"location"`
+	Date     int    `xml:"date"` // julian day, 1 is January 1 of year 1
+}
+
+type rhythmboxPlaylists struct {
+	XMLName   xml.Name `xml:"rhythmdb-playlists"`
+	Playlists []struct {
+		Name      string   `xml:"name,attr"`
+		Type      string   `xml:"type,attr"`
+		Locations []string `xml:"location"`
+	} `xml:"playlist"`
+}
+
+// julianEpoch is the day before julian day 1
+var julianEpoch = time.Date(0, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// ParseRhythmbox reads rhythmdb.xml and optional playlists.xml, tracks are identified by location
+func ParseRhythmbox(r io.Reader, playlists io.Reader) (*Library, error) {
+	var db rhythmdb
+	if err := xml.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("invalid rhythmdb: %w", err)
+	}
+
+	lib := &Library{Source: SourceRhythmbox}
+	for _, e := range db.Entries {
+		track := Track{
+			ID:       e.Location,
+			Artist:   strings.TrimSpace(e.Artist),
+			Name:     strings.TrimSpace(e.Title),
+			Genre:    strings.TrimSpace(e.Genre),
+			Album:    strings.TrimSpace(e.Album),
+			Location: e.Location,
+		}
+		if e.Date > 0 {
+			// rhythmbox keeps the year of the tag as January 1
+			track.Year = julianEpoch.AddDate(0, 0, e.Date).Year()
+		}
+		if e.Type != "song" {
+			track.Skip = "not a song: " + e.Type
+		}
+		lib.Tracks = append(lib.Tracks, track)
+	}
+
+	if playlists == nil {
+		return lib, nil
+	}
+	var doc rhythmboxPlaylists
+	if err := xml.NewDecoder(playlists).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid rhythmbox playlists: %w", err)
+	}
+	for _, p := range doc.Playlists {
+		if p.Type != "static" { // automatic playlists are queries, not lists
+			continue
+		}
+		lib.Playlists = append(lib.Playlists, Playlist{Name: p.Name, TrackIDs: p.Locations})
+	}
+	return lib, nil
+}
@@ -0,0 +1,379 @@
+// Package linkcheck probes song links in the background and records which of them are broken
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"music_library_api/internal/links"
+	"music_library_api/internal/models"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMaxAge      = 24 * time.Hour
+	defaultConcurrency = 4
+	defaultHostDelay   = time.Second
+	defaultTimeout     = 10 * time.Second
+	userAgent          = "music_library_api link checker"
+	maxBodyRead        = 64 << 10 // of a GET probe, the rest is dropped
+)
+
+// Config of the checker
+type Config struct {
+	Interval    time.Duration // between runs, 0 - the worker is disabled
+	MaxAge      time.Duration // links checked earlier are probed again
+	Concurrency int           // probes at a time
+	HostDelay   time.Duration // between probes of one host
+	Timeout     time.Duration // of one probe with its redirects
+	BaseURL     *url.URL      // if set, every probe goes to this server with the link host in the Host header, e.g. a local stub; without it only public addresses are probed
+}
+
+// ConfigFromEnv reads LINKCHECK_* settings, invalid durations and numbers fall back to defaults
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+	cfg.Interval, _ = time.ParseDuration(os.Getenv("LINKCHECK_INTERVAL"))
+	cfg.MaxAge, _ = time.ParseDuration(os.Getenv("LINKCHECK_MAX_AGE"))
+	cfg.Concurrency, _ = strconv.Atoi(os.Getenv("LINKCHECK_CONCURRENCY"))
+	cfg.HostDelay, _ = time.ParseDuration(os.Getenv("LINKCHECK_HOST_DELAY"))
+	cfg.Timeout, _ = time.ParseDuration(os.Getenv("LINKCHECK_TIMEOUT"))
+	if v := os.Getenv("LINKCHECK_BASE_URL"); v != "" {
+		base, err := url.Parse(v)
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+			return cfg, fmt.Errorf("invalid LINKCHECK_BASE_URL %q, expected http(s)://host[:port]", v)
+		}
+		cfg.BaseURL = base
+	}
+	return cfg, nil
+}
+
+// Result of one probe
+type Result struct {
+	Status     string // models.LinkStatusOK or LinkStatusBroken
+	HTTPStatus int
+	Error      string
+	Deferred   bool // rate limited or cancelled, nothing is recorded and the link is probed next run
+}
+
+// Summary of a run
+type Summary struct {
+	Checked  int `json:"checked"`
+	OK       int `json:"ok"`
+	Broken   int `json:"broken"`
+	Deferred int `json:"deferred"`
+}
+
+// Checker probes links and saves the results
+type Checker struct {
+	db     *gorm.DB
+	cfg    Config
+	client *http.Client
+	gate   *politeness
+}
+
+func New(db *gorm.DB, cfg Config) *Checker {
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaultMaxAge
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	if cfg.HostDelay < 0 {
+		cfg.HostDelay = 0
+	} else if cfg.HostDelay == 0 {
+		cfg.HostDelay = defaultHostDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	var transport http.RoundTripper
+	if cfg.BaseURL != nil {
+		transport = rewriteTransport{base: cfg.BaseURL, next: http.DefaultTransport}
+	} else {
+		transport = publicTransport()
+	}
+	return &Checker{
+		db:     db,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		gate:   &politeness{delay: cfg.HostDelay, next: map[string]time.Time{}},
+	}
+}
+
+// StartWorker checks due links right away and then on every tick, a zero interval disables it
+func StartWorker(db *gorm.DB, cfg Config) {
+	if cfg.Interval <= 0 {
+		log.Println("Info: Link checker is disabled")
+		return
+	}
+	checker := New(db, cfg)
+	log.Printf("Info: Link checker started, links older than %s, every %s, %d at a time",
+		checker.cfg.MaxAge, cfg.Interval, checker.cfg.Concurrency)
+
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			summary, err := checker.Run(context.Background())
+			if err != nil {
+				log.Printf("Error: Link check failed: %v", err)
+			} else if summary.Checked > 0 {
+				log.Printf("Info: Checked %d links: %d ok, %d broken, %d deferred",
+					summary.Checked, summary.OK, summary.Broken, summary.Deferred)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Run probes links never checked or checked longer than MaxAge ago, links of deleted songs are skipped
+func (c *Checker) Run(ctx context.Context) (Summary, error) {
+	var due []models.SongLink
+	err := c.db.Where("checked_at IS NULL OR checked_at < ?", time.Now().Add(-c.cfg.MaxAge)).
+		Where("EXISTS (SELECT 1 FROM songs WHERE songs.id = song_links.song_id)").
+		Order("checked_at NULLS FIRST, id").Find(&due).Error
+	if err != nil {
+		return Summary{}, err
+	}
+	return c.Check(ctx, due)
+}
+
+// Check probes the links and saves the results, links of one host are spread over the run
+func (c *Checker) Check(ctx context.Context, list []models.SongLink) (Summary, error) {
+	var (
+		summary Summary
+		mu      sync.Mutex
+		saveErr error
+		wg      sync.WaitGroup
+	)
+	queue := make(chan models.SongLink)
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range queue {
+				result := c.Probe(ctx, link)
+				var err error
+				if !result.Deferred {
+					err = c.record(link, result)
+				}
+
+				mu.Lock()
+				summary.Checked++
+				switch {
+				case result.Deferred:
+					summary.Deferred++
+				case result.Status == models.LinkStatusOK:
+					summary.OK++
+				default:
+					summary.Broken++
+				}
+				if err != nil && saveErr == nil {
+					saveErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, link := range interleave(list) {
+		queue <- link
+	}
+	close(queue)
+	wg.Wait()
+	return summary, saveErr
+}
+
+// record saves a probe result
+func (c *Checker) record(link models.SongLink, result Result) error {
+	return c.db.Model(&models.SongLink{}).Where("id = ?", link.ID).Updates(resultUpdates(result, time.Now())).Error
+}
+
+// resultUpdates are the columns of a probe result, a failure extends the streak and a success resets it
+func resultUpdates(result Result, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"status":      result.Status,
+		"http_status": result.HTTPStatus,
+		"check_error": result.Error,
+		"checked_at":  now,
+	}
+	if result.Status == models.LinkStatusOK {
+		updates["failure_streak"] = 0
+		updates["last_ok_at"] = now
+	} else {
+		updates["failure_streak"] = gorm.Expr("failure_streak + 1")
+	}
+	return updates
+}
+
+// oEmbed endpoints answer 404 for removed items, while the pages themselves are served with 200 anyway
+var oEmbed = map[string]string{
+	links.PlatformYouTube:    "https://www.youtube.com/oembed?format=json&url=",
+	links.PlatformSpotify:    "https://open.spotify.com/oembed?url=",
+	links.PlatformSoundCloud: "https://soundcloud.com/oembed?format=json&url=",
+}
+
+// Probe requests the link, HEAD first and GET if the server does not answer HEAD properly
+func (c *Checker) Probe(ctx context.Context, link models.SongLink) Result {
+	target, err := url.Parse(link.URL)
+	if err != nil {
+		return Result{Status: models.LinkStatusBroken, Error: err.Error()}
+	}
+	if err := c.gate.wait(ctx, target.Host); err != nil {
+		return Result{Deferred: true}
+	}
+
+	if endpoint, ok := oEmbed[link.Platform]; ok {
+		status, err := c.request(ctx, http.MethodGet, endpoint+url.QueryEscape(link.URL))
+		// private and not embeddable items exist
+		return classify(status, err, status == http.StatusUnauthorized || status == http.StatusForbidden)
+	}
+	status, err := c.request(ctx, http.MethodHead, link.URL)
+	if err == nil && headUnsupported(status) {
+		status, err = c.request(ctx, http.MethodGet, link.URL)
+	}
+	return classify(status, err, false)
+}
+
+func (c *Checker) request(ctx context.Context, method, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyRead))
+	return resp.StatusCode, nil
+}
+
+// headUnsupported are answers of servers that refuse HEAD but may serve GET
+func headUnsupported(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+func classify(status int, err error, exists bool) Result {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Result{Deferred: true}
+	case err != nil:
+		return Result{Status: models.LinkStatusBroken, Error: err.Error()}
+	case status == http.StatusTooManyRequests:
+		return Result{HTTPStatus: status, Deferred: true}
+	case status < 400 || exists:
+		return Result{Status: models.LinkStatusOK, HTTPStatus: status}
+	}
+	return Result{Status: models.LinkStatusBroken, HTTPStatus: status, Error: http.StatusText(status)}
+}
+
+// interleave orders links round-robin by host, so workers are not all waiting for one host
+func interleave(list []models.SongLink) []models.SongLink {
+	var hosts []string
+	byHost := map[string][]models.SongLink{}
+	for _, link := range list {
+		host := link.URL
+		if u, err := url.Parse(link.URL); err == nil {
+			host = u.Host
+		}
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], link)
+	}
+	ordered := make([]models.SongLink, 0, len(list))
+	for len(ordered) < len(list) {
+		for _, host := range hosts {
+			if rest := byHost[host]; len(rest) > 0 {
+				ordered = append(ordered, rest[0])
+				byHost[host] = rest[1:]
+			}
+		}
+	}
+	return ordered
+}
+
+// politeness hands out probe times per host, each one delay after the previous
+type politeness struct {
+	mu    sync.Mutex
+	delay time.Duration
+	next  map[string]time.Time
+}
+
+func (p *politeness) wait(ctx context.Context, host string) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	p.next[host] = at.Add(p.delay)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errNotPublic is returned for connections to loopback, private, link-local and reserved addresses
+var errNotPublic = errors.New("address is not public")
+
+// publicTransport connects to public addresses only. The check is made on the resolved address at dial time,
+// so redirects and host names pointing inside the network are refused as well
+func publicTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would connect to any address on our behalf
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialPublic,
+	}).DialContext
+	return transport
+}
+
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !links.PublicIP(addr.Addr()) {
+		return fmt.Errorf("%s: %w", addr.Addr(), errNotPublic)
+	}
+	return nil
+}
+
+// rewriteTransport sends requests to the base server, the original host goes in the Host header
+type rewriteTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = t.base.Scheme
+	out.URL.Host = t.base.Host
+	out.Host = req.URL.Host
+	return t.next.RoundTrip(out)
+}
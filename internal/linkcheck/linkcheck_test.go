@@ -0,0 +1,259 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"music_library_api/internal/links"
+	"music_library_api/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// stub serves every probe through rewriteTransport, handler sees the link host in r.Host
+func stub(t *testing.T, handler http.HandlerFunc) *Checker {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	base, _ := url.Parse(server.URL)
+	return New(nil, Config{BaseURL: base, HostDelay: -1, Timeout: 2 * time.Second})
+}
+
+func TestProbeHeadRefused(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	checker := stub(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	})
+
+	result := checker.Probe(context.Background(), models.SongLink{Platform: links.PlatformOther, URL: "https://example.com/song"})
+	if result.Status != models.LinkStatusOK || result.HTTPStatus != http.StatusOK {
+		t.Errorf("Probe() = %+v, want ok 200", result)
+	}
+	if got := strings.Join(methods, ","); got != "HEAD,GET" {
+		t.Errorf("methods = %s, want HEAD,GET", got)
+	}
+}
+
+func TestProbeStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   Result
+	}{
+		{"ok", http.StatusOK, Result{Status: models.LinkStatusOK, HTTPStatus: http.StatusOK}},
+		{"not modified", http.StatusNotModified, Result{Status: models.LinkStatusOK, HTTPStatus: http.StatusNotModified}},
+		{"gone", http.StatusGone, Result{Status: models.LinkStatusBroken, HTTPStatus: http.StatusGone, Error: "Gone"}},
+		{"server error", http.StatusBadGateway, Result{Status: models.LinkStatusBroken, HTTPStatus: http.StatusBadGateway, Error: "Bad Gateway"}},
+		{"rate limited", http.StatusTooManyRequests, Result{HTTPStatus: http.StatusTooManyRequests, Deferred: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := stub(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+			got := checker.Probe(context.Background(), models.SongLink{Platform: links.PlatformOther, URL: "https://example.com/song"})
+			if got != tt.want {
+				t.Errorf("Probe() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeRedirect(t *testing.T) {
+	checker := stub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "https://cdn.example.com/song", http.StatusMovedPermanently)
+		case "/removed":
+			http.Redirect(w, r, "/gone", http.StatusFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		default:
+			if r.Host != "cdn.example.com" {
+				t.Errorf("redirect went to %s, want cdn.example.com", r.Host)
+			}
+		}
+	})
+	tests := map[string]Result{
+		"https://example.com/moved":   {Status: models.LinkStatusOK, HTTPStatus: http.StatusOK},
+		"https://example.com/removed": {Status: models.LinkStatusBroken, HTTPStatus: http.StatusGone, Error: "Gone"},
+	}
+	for link, want := range tests {
+		if got := checker.Probe(context.Background(), models.SongLink{Platform: links.PlatformOther, URL: link}); got != want {
+			t.Errorf("Probe(%s) = %+v, want %+v", link, got, want)
+		}
+	}
+}
+
+func TestProbeOEmbed(t *testing.T) {
+	const video = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	tests := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{"available", http.StatusOK, models.LinkStatusOK},
+		{"removed", http.StatusNotFound, models.LinkStatusBroken},
+		{"private", http.StatusUnauthorized, models.LinkStatusOK},
+		{"not embeddable", http.StatusForbidden, models.LinkStatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := stub(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Host != "www.youtube.com" || r.URL.Path != "/oembed" || r.URL.Query().Get("url") != video {
+					t.Errorf("request to %s%s, want the oEmbed endpoint of the video", r.Host, r.URL)
+				}
+				w.WriteHeader(tt.status)
+			})
+			got := checker.Probe(context.Background(), models.SongLink{Platform: links.PlatformYouTube, URL: video})
+			if got.Status != tt.want || got.HTTPStatus != tt.status {
+				t.Errorf("Probe() = %+v, want %s %d", got, tt.want, tt.status)
+			}
+		})
+	}
+}
+
+func TestProbeHostDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	var (
+		mu    sync.Mutex
+		times = map[string][]time.Time{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times[r.Host] = append(times[r.Host], time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+	base, _ := url.Parse(server.URL)
+	checker := New(nil, Config{BaseURL: base, HostDelay: delay, Timeout: 2 * time.Second})
+
+	var wg sync.WaitGroup
+	for _, link := range []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://b.example/1"} {
+		wg.Add(1)
+		go func(link string) {
+			defer wg.Done()
+			checker.Probe(context.Background(), models.SongLink{Platform: links.PlatformOther, URL: link})
+		}(link)
+	}
+	wg.Wait()
+
+	a := times["a.example"]
+	if len(a) != 3 {
+		t.Fatalf("a.example got %d probes, want 3", len(a))
+	}
+	for i := 1; i < len(a); i++ {
+		// timer resolution leaves a little slack
+		if gap := a[i].Sub(a[i-1]); gap < delay-10*time.Millisecond {
+			t.Errorf("probes %d and %d of one host %s apart, want at least %s", i-1, i, gap, delay)
+		}
+	}
+	if b := times["b.example"]; len(b) != 1 || b[0].Sub(a[0]) >= delay {
+		t.Errorf("other host waited for a.example")
+	}
+}
+
+func TestProbeCancelled(t *testing.T) {
+	checker := stub(t, func(w http.ResponseWriter, r *http.Request) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got := checker.Probe(ctx, models.SongLink{Platform: links.PlatformOther, URL: "https://example.com/song"})
+	if !got.Deferred {
+		t.Errorf("Probe() = %+v, want deferred", got)
+	}
+}
+
+func TestProbeNotPublic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request reached the local server")
+	}))
+	defer server.Close()
+	checker := New(nil, Config{HostDelay: -1, Timeout: 2 * time.Second})
+
+	got := checker.Probe(context.Background(), models.SongLink{Platform: links.PlatformOther, URL: server.URL + "/admin"})
+	if got.Status != models.LinkStatusBroken || !strings.Contains(got.Error, errNotPublic.Error()) {
+		t.Errorf("Probe() = %+v, want broken with %q", got, errNotPublic)
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.1:80", false},
+		{"172.16.5.4:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+	}
+	for _, tt := range tests {
+		err := dialPublic("tcp", tt.address, nil)
+		if tt.public && err != nil {
+			t.Errorf("dialPublic(%s) = %v, want nil", tt.address, err)
+		}
+		if !tt.public && !errors.Is(err, errNotPublic) {
+			t.Errorf("dialPublic(%s) = %v, want errNotPublic", tt.address, err)
+		}
+	}
+}
+
+func TestResultUpdates(t *testing.T) {
+	now := time.Now()
+
+	ok := resultUpdates(Result{Status: models.LinkStatusOK, HTTPStatus: http.StatusOK}, now)
+	if ok["failure_streak"] != 0 || ok["last_ok_at"] != now || ok["checked_at"] != now || ok["status"] != models.LinkStatusOK {
+		t.Errorf("ok updates = %v, want streak reset and lastOkAt set", ok)
+	}
+
+	broken := resultUpdates(Result{Status: models.LinkStatusBroken, HTTPStatus: http.StatusGone, Error: "Gone"}, now)
+	expr, isExpr := broken["failure_streak"].(clause.Expr)
+	if !isExpr || expr.SQL != "failure_streak + 1" {
+		t.Errorf("broken failure_streak = %v, want failure_streak + 1", broken["failure_streak"])
+	}
+	if _, set := broken["last_ok_at"]; set {
+		t.Errorf("broken updates set last_ok_at")
+	}
+	if broken["check_error"] != "Gone" || broken["http_status"] != http.StatusGone || broken["checked_at"] != now {
+		t.Errorf("broken updates = %v", broken)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	var list []models.SongLink
+	for _, u := range []string{"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://b.example/1", "https://c.example/1"} {
+		list = append(list, models.SongLink{URL: u})
+	}
+	var got []string
+	for _, link := range interleave(list) {
+		got = append(got, link.URL)
+	}
+	want := "https://a.example/1 https://b.example/1 https://c.example/1 https://a.example/2 https://a.example/3"
+	if strings.Join(got, " ") != want {
+		t.Errorf("interleave() = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,336 @@
+// Package links validates external song links and brings them to one canonical form per platform
+package links
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// link platforms
+const (
+	PlatformYouTube    = "youtube"
+	PlatformSpotify    = "spotify"
+	PlatformAppleMusic = "applemusic"
+	PlatformBandcamp   = "bandcamp"
+	PlatformSoundCloud = "soundcloud"
+	PlatformOther      = "other"
+)
+
+// Platforms in the order links are listed
+var Platforms = []string{PlatformYouTube, PlatformSpotify, PlatformAppleMusic, PlatformBandcamp, PlatformSoundCloud, PlatformOther}
+
+// ErrInvalid wraps every validation error
+var ErrInvalid = errors.New("invalid link")
+
+// Link is a validated link
+type Link struct {
+	Platform   string
+	URL        string // canonical, equal links of different forms get the same URL
+	ExternalID string // ID on the platform: YouTube video, Spotify URI, Apple Music item; empty if the URL has none
+	EmbedURL   string // player for an iframe, empty if the platform has none for this URL
+}
+
+var (
+	youTubeID     = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	spotifyID     = regexp.MustCompile(`^[A-Za-z0-9]{22}$`)
+	appleID       = regexp.MustCompile(`^[0-9]+$`)
+	applePlaylist = regexp.MustCompile(`^pl\.[A-Za-z0-9._-]+$`)
+	countryCode   = regexp.MustCompile(`^[a-z]{2}$`)
+	slug          = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+)
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
+
+// KnownPlatform reports whether p is one of Platforms
+func KnownPlatform(p string) bool {
+	for _, known := range Platforms {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect tells the platform of a URL by its host, PlatformOther for unknown hosts
+func Detect(u *url.URL) string {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch {
+	case u.Scheme == "spotify":
+		return PlatformSpotify
+	case host == "youtube.com" || host == "m.youtube.com" || host == "music.youtube.com" ||
+		host == "youtu.be" || host == "youtube-nocookie.com":
+		return PlatformYouTube
+	case host == "open.spotify.com" || host == "play.spotify.com":
+		return PlatformSpotify
+	case host == "music.apple.com" || host == "geo.music.apple.com" || host == "embed.music.apple.com" || host == "itunes.apple.com":
+		return PlatformAppleMusic
+	case strings.HasSuffix(host, ".bandcamp.com"):
+		return PlatformBandcamp
+	case host == "soundcloud.com" || host == "m.soundcloud.com" || host == "on.soundcloud.com":
+		return PlatformSoundCloud
+	}
+	return PlatformOther
+}
+
+// Parse validates a link and canonicalizes it. The platform is detected from the host,
+// a non-empty platform other than PlatformOther must match it
+func Parse(raw, platform string) (Link, error) {
+	raw = strings.TrimSpace(raw)
+	if platform != "" && !KnownPlatform(platform) {
+		return Link{}, invalid("unknown platform %q, use one of %s", platform, strings.Join(Platforms, ", "))
+	}
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return Link{}, invalid("expected http(s) URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "spotify" && ((u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+		return Link{}, invalid("expected http(s) URL")
+	}
+
+	detected := Detect(u)
+	if platform != "" && platform != PlatformOther && platform != detected {
+		return Link{}, invalid("%s is not a %s URL", raw, platform)
+	}
+	switch detected {
+	case PlatformYouTube:
+		return youTube(u)
+	case PlatformSpotify:
+		return spotify(u)
+	case PlatformAppleMusic:
+		return appleMusic(u)
+	case PlatformBandcamp:
+		return bandcamp(u)
+	case PlatformSoundCloud:
+		return soundCloud(u)
+	}
+	if !publicHost(u.Hostname()) {
+		return Link{}, invalid("link must point to a public host")
+	}
+	return other(u), nil
+}
+
+// nonPublic are ranges that PublicIP does not tell by itself: shared, benchmarking and reserved
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// PublicIP reports whether ip is reachable on the internet, not loopback, private, link-local or reserved
+func PublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublic {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicHost rejects localhost and non-public IP literals, names are resolved only when requested
+func publicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return PublicIP(ip)
+	}
+	// inet_aton forms like 2130706433 or 0x7f.1 are IPs to some resolvers
+	for _, part := range strings.Split(host, ".") {
+		if _, err := strconv.ParseUint(part, 0, 32); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// segments splits the path, empty segments are dropped
+func segments(u *url.URL) []string {
+	var parts []string
+	for _, p := range strings.Split(u.Path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// youTube accepts watch, youtu.be, embed, shorts and live URLs of a video
+func youTube(u *url.URL) (Link, error) {
+	parts := segments(u)
+	var id string
+	switch {
+	case strings.EqualFold(u.Hostname(), "youtu.be") && len(parts) == 1:
+		id = parts[0]
+	case len(parts) == 1 && parts[0] == "watch":
+		id = u.Query().Get("v")
+	case len(parts) == 2 && (parts[0] == "embed" || parts[0] == "shorts" || parts[0] == "live" || parts[0] == "v"):
+		id = parts[1]
+	}
+	if !youTubeID.MatchString(id) {
+		return Link{}, invalid("YouTube link must point to a video")
+	}
+	return Link{
+		Platform:   PlatformYouTube,
+		URL:        "https://www.youtube.com/watch?v=" + id,
+		ExternalID: id,
+		EmbedURL:   "https://www.youtube.com/embed/" + id,
+	}, nil
+}
+
+// spotify accepts open.spotify.com URLs and spotify: URIs of tracks, albums, artists and playlists
+func spotify(u *url.URL) (Link, error) {
+	var parts []string
+	if u.Scheme == "spotify" {
+		parts = strings.Split(u.Opaque, ":")
+	} else {
+		parts = segments(u)
+		if len(parts) > 0 && strings.HasPrefix(parts[0], "intl-") {
+			parts = parts[1:]
+		}
+		if len(parts) > 0 && parts[0] == "embed" {
+			parts = parts[1:]
+		}
+	}
+	if len(parts) != 2 || !spotifyID.MatchString(parts[1]) {
+		return Link{}, invalid("Spotify link must point to a track, album, artist or playlist")
+	}
+	kind, id := parts[0], parts[1]
+	switch kind {
+	case "track", "album", "artist", "playlist":
+	default:
+		return Link{}, invalid("Spotify link must point to a track, album, artist or playlist")
+	}
+	return Link{
+		Platform:   PlatformSpotify,
+		URL:        "https://open.spotify.com/" + kind + "/" + id,
+		ExternalID: "spotify:" + kind + ":" + id,
+		EmbedURL:   "https://open.spotify.com/embed/" + kind + "/" + id,
+	}, nil
+}
+
+// appleMusic accepts /{country}/{album|song|playlist}/{name}/{id} URLs, ?i= selects a track of an album
+func appleMusic(u *url.URL) (Link, error) {
+	parts := segments(u)
+	country := ""
+	if len(parts) > 0 && countryCode.MatchString(parts[0]) {
+		country, parts = parts[0], parts[1:]
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return Link{}, invalid("Apple Music link must point to a song, album or playlist")
+	}
+	kind, id := parts[0], strings.TrimPrefix(parts[len(parts)-1], "id") // iTunes Store links have id123
+	name := ""
+	if len(parts) == 3 {
+		name = parts[1]
+		if !slug.MatchString(name) {
+			name = url.PathEscape(name)
+		}
+	}
+	switch {
+	case (kind == "album" || kind == "song" || kind == "music-video") && appleID.MatchString(id):
+	case kind == "playlist" && applePlaylist.MatchString(id):
+	default:
+		return Link{}, invalid("Apple Music link must point to a song, album or playlist")
+	}
+
+	path := "/"
+	if country != "" {
+		path += country + "/"
+	}
+	path += kind + "/"
+	if name != "" {
+		path += name + "/"
+	}
+	path += id
+	externalID := id
+	if track := u.Query().Get("i"); kind == "album" && appleID.MatchString(track) {
+		path += "?i=" + track
+		externalID = track
+	}
+	return Link{
+		Platform:   PlatformAppleMusic,
+		URL:        "https://music.apple.com" + path,
+		ExternalID: externalID,
+		EmbedURL:   "https://embed.music.apple.com" + path,
+	}, nil
+}
+
+// bandcamp accepts track and album pages, the embedded player needs numeric IDs that pages do not show
+func bandcamp(u *url.URL) (Link, error) {
+	parts := segments(u)
+	artist := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".bandcamp.com")
+	if len(parts) != 2 || (parts[0] != "track" && parts[0] != "album") || !slug.MatchString(parts[1]) ||
+		artist == "" || strings.Contains(artist, ".") {
+		return Link{}, invalid("Bandcamp link must point to a track or album")
+	}
+	return Link{
+		Platform: PlatformBandcamp,
+		URL:      "https://" + artist + ".bandcamp.com/" + parts[0] + "/" + parts[1],
+	}, nil
+}
+
+// soundCloudReserved are first path segments that are not user names
+var soundCloudReserved = map[string]bool{
+	"discover": true, "search": true, "stream": true, "upload": true, "you": true,
+	"charts": true, "settings": true, "messages": true, "notifications": true, "pages": true,
+}
+
+// soundCloud accepts /{user}/{track}, /{user}/sets/{set}, their private /s-{token} forms and on.soundcloud.com short links
+func soundCloud(u *url.URL) (Link, error) {
+	parts := segments(u)
+	if strings.EqualFold(u.Hostname(), "on.soundcloud.com") {
+		if len(parts) != 1 || !slug.MatchString(parts[0]) {
+			return Link{}, invalid("SoundCloud short link must have one path segment")
+		}
+		return Link{Platform: PlatformSoundCloud, URL: "https://on.soundcloud.com/" + parts[0]}, nil
+	}
+
+	n := len(parts)
+	if n > 0 && strings.HasPrefix(parts[n-1], "s-") {
+		n-- // secret token of a private track
+	}
+	ok := (n == 2 && parts[1] != "sets") || (n == 3 && parts[1] == "sets")
+	if ok && soundCloudReserved[strings.ToLower(parts[0])] {
+		ok = false
+	}
+	for _, p := range parts {
+		ok = ok && slug.MatchString(p)
+	}
+	if !ok {
+		return Link{}, invalid("SoundCloud link must point to a track or set")
+	}
+	canonical := "https://soundcloud.com/" + strings.Join(parts, "/")
+	return Link{
+		Platform: PlatformSoundCloud,
+		URL:      canonical,
+		EmbedURL: "https://w.soundcloud.com/player/?url=" + url.QueryEscape(canonical),
+	}, nil
+}
+
+// other normalizes scheme and host and drops the fragment, the rest is kept as is
+func other(u *url.URL) Link {
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return Link{Platform: PlatformOther, URL: u.String()}
+}
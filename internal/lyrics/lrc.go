@@ -0,0 +1,240 @@
+package lyrics
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lastLineMs duration of the last line when LRC has no [length:] tag
+const lastLineMs = 5000
+
+// SyncedWord is one word of enhanced LRC
+type SyncedWord struct {
+	StartMs int    `json:"startMs"`
+	EndMs   int    `json:"endMs"`
+	Text    string `json:"text"`
+}
+
+// SyncedLine is one timed lyric line
+type SyncedLine struct {
+	StartMs int          `json:"startMs"`
+	EndMs   int          `json:"endMs"`
+	Text    string       `json:"text"`
+	Words   []SyncedWord `json:"words,omitempty"`
+}
+
+// LRC is parsed LRC or enhanced LRC
+type LRC struct {
+	Tags     map[string]string `json:"tags,omitempty"` // ar, ti, al, length...
+	Enhanced bool              `json:"enhanced"`       // has word-level timing
+	Lines    []SyncedLine      `json:"lines"`
+}
+
+var (
+	lineTimeRe = regexp.MustCompile(`^\[(\d{1,3}):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	wordTimeRe = regexp.MustCompile(`<(\d{1,3}):(\d{1,2})(?:[.:](\d{1,3}))?>`)
+	tagRe      = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]$`)
+)
+
+// toMs converts mm, ss and fraction (.x, .xx or .xxx) to milliseconds
+func toMs(min, sec, frac string) (int, error) {
+	m, err := strconv.Atoi(min)
+	if err != nil {
+		return 0, err
+	}
+	s, err := strconv.Atoi(sec)
+	if err != nil {
+		return 0, err
+	}
+	if s >= 60 {
+		return 0, fmt.Errorf("seconds out of range: %d", s)
+	}
+	ms := 0
+	if frac != "" {
+		f, err := strconv.Atoi(frac)
+		if err != nil {
+			return 0, err
+		}
+		switch len(frac) {
+		case 1:
+			ms = f * 100
+		case 2:
+			ms = f * 10
+		default:
+			ms = f
+		}
+	}
+	return (m*60+s)*1000 + ms, nil
+}
+
+// ParseLRC validates and parses LRC text, line numbers in errors start from 1
+func ParseLRC(raw string) (*LRC, error) {
+	lrc := &LRC{Tags: map[string]string{}}
+	offset := 0
+
+	// editors may save the file with a BOM, it would fail the first line
+	scanner := bufio.NewScanner(strings.NewReader(strings.TrimPrefix(raw, "\ufeff")))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// one line may have several timestamps: [00:12.00][01:30.00]chorus
+		var starts []int
+		for {
+			m := lineTimeRe.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			ms, err := toMs(m[1], m[2], m[3])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid timestamp %s: %v", n, m[0], err)
+			}
+			starts = append(starts, ms)
+			line = line[len(m[0]):]
+		}
+
+		if len(starts) == 0 {
+			m := tagRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: expected [mm:ss.xx] timestamp or [tag:value]", n)
+			}
+			key := strings.ToLower(m[1])
+			value := strings.TrimSpace(m[2])
+			lrc.Tags[key] = value
+			if key == "offset" {
+				v, err := strconv.Atoi(strings.TrimPrefix(value, "+"))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid offset %q", n, value)
+				}
+				offset = v
+			}
+			continue
+		}
+
+		text, words, err := parseWords(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if len(words) > 0 {
+			lrc.Enhanced = true
+		}
+		for _, start := range starts {
+			lrc.Lines = append(lrc.Lines, SyncedLine{StartMs: start, Text: text, Words: shiftWords(words, start, starts[0])})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lrc.Lines) == 0 {
+		return nil, fmt.Errorf("no timed lines found")
+	}
+
+	sort.SliceStable(lrc.Lines, func(i, j int) bool { return lrc.Lines[i].StartMs < lrc.Lines[j].StartMs })
+
+	end := -1
+	if length, ok := lrc.Tags["length"]; ok {
+		parts := strings.SplitN(length, ":", 2)
+		if len(parts) == 2 {
+			sec := strings.SplitN(parts[1], ".", 2)
+			frac := ""
+			if len(sec) == 2 {
+				frac = sec[1]
+			}
+			if ms, err := toMs(parts[0], sec[0], frac); err == nil {
+				end = ms
+			}
+		}
+	}
+	// positive offset shows lyrics earlier
+	for i := range lrc.Lines {
+		l := &lrc.Lines[i]
+		l.StartMs -= offset
+		for j := range l.Words {
+			l.Words[j].StartMs -= offset
+		}
+	}
+	for i := range lrc.Lines {
+		l := &lrc.Lines[i]
+		switch {
+		case i+1 < len(lrc.Lines):
+			l.EndMs = lrc.Lines[i+1].StartMs
+		case end-offset > l.StartMs:
+			l.EndMs = end - offset
+		default:
+			l.EndMs = l.StartMs + lastLineMs
+		}
+		for j := range l.Words {
+			if j+1 < len(l.Words) {
+				l.Words[j].EndMs = l.Words[j+1].StartMs
+			} else {
+				l.Words[j].EndMs = l.EndMs
+			}
+		}
+	}
+	return lrc, nil
+}
+
+// parseWords extracts <mm:ss.xx> word timing of enhanced LRC
+func parseWords(line string) (string, []SyncedWord, error) {
+	locs := wordTimeRe.FindAllStringSubmatchIndex(line, -1)
+	if len(locs) == 0 {
+		return strings.TrimSpace(line), nil, nil
+	}
+
+	var words []SyncedWord
+	var text strings.Builder
+	text.WriteString(line[:locs[0][0]])
+	for i, loc := range locs {
+		ms, err := toMs(line[loc[2]:loc[3]], line[loc[4]:loc[5]], sub(line, loc[6], loc[7]))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid word timestamp %s: %v", line[loc[0]:loc[1]], err)
+		}
+		next := len(line)
+		if i+1 < len(locs) {
+			next = locs[i+1][0]
+		}
+		word := line[loc[1]:next]
+		text.WriteString(word)
+		if strings.TrimSpace(word) != "" {
+			words = append(words, SyncedWord{StartMs: ms, Text: strings.TrimSpace(word)})
+		}
+	}
+	return strings.TrimSpace(text.String()), words, nil
+}
+
+func sub(s string, from, to int) string {
+	if from < 0 {
+		return ""
+	}
+	return s[from:to]
+}
+
+// shiftWords moves word timing of a repeated line to its other start
+func shiftWords(words []SyncedWord, start, first int) []SyncedWord {
+	if len(words) == 0 {
+		return nil
+	}
+	shifted := make([]SyncedWord, len(words))
+	for i, w := range words {
+		shifted[i] = SyncedWord{StartMs: w.StartMs + start - first, Text: w.Text}
+	}
+	return shifted
+}
+
+// At returns the index of the line playing at ms, -1 if none
+func (l *LRC) At(ms int) int {
+	i := sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].StartMs > ms }) - 1
+	if i < 0 || ms >= l.Lines[i].EndMs {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,86 @@
+package lyrics
+
+import (
+	"strings"
+)
+
+// Verses splits song text into verses on blank lines
+func Verses(text string) []string {
+	return strings.Split(text, "\n\n")
+}
+
+// Lines splits a verse into lines
+func Lines(verse string) []string {
+	return strings.Split(verse, "\n")
+}
+
+// Anchor points to a character range [Start, End) of one line, counted in runes
+type Anchor struct {
+	Verse int
+	Line  int
+	Start int
+	End   int
+	Quote string // anchored text at the time of anchoring
+}
+
+// lineAt returns the line by verse and line index
+func lineAt(text string, verse, line int) ([]rune, bool) {
+	verses := Verses(text)
+	if verse < 0 || verse >= len(verses) {
+		return nil, false
+	}
+	lines := Lines(verses[verse])
+	if line < 0 || line >= len(lines) {
+		return nil, false
+	}
+	return []rune(lines[line]), true
+}
+
+// Quote returns the anchored text, false if the anchor is out of the text
+func Quote(text string, verse, line, start, end int) (string, bool) {
+	runes, ok := lineAt(text, verse, line)
+	if !ok || start < 0 || end > len(runes) || start >= end {
+		return "", false
+	}
+	return string(runes[start:end]), true
+}
+
+// Reanchor finds the quote of an anchor in changed text.
+// The old position wins if the quote is still there, otherwise the
+// closest occurrence is taken. false means the quote is gone (orphaned).
+func Reanchor(text string, a Anchor) (Anchor, bool) {
+	if q, ok := Quote(text, a.Verse, a.Line, a.Start, a.End); ok && q == a.Quote {
+		return a, true
+	}
+	if a.Quote == "" {
+		return a, false
+	}
+
+	quote := []rune(a.Quote)
+	found := false
+	best := a
+	bestDist := 0
+	for v, verse := range Verses(text) {
+		for l, line := range Lines(verse) {
+			idx := strings.Index(line, a.Quote)
+			if idx < 0 {
+				continue
+			}
+			start := len([]rune(line[:idx]))
+			dist := abs(v-a.Verse)*1000 + abs(l-a.Line)*10 + abs(start-a.Start)
+			if !found || dist < bestDist {
+				found = true
+				bestDist = dist
+				best = Anchor{Verse: v, Line: l, Start: start, End: start + len(quote), Quote: a.Quote}
+			}
+		}
+	}
+	return best, found
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
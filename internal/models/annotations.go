@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// Annotation is a comment on a character range of one lyric line
+type Annotation struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	SongID    int       `json:"songId" gorm:"column:song_id;index"`
+	UserID    string    `json:"author" gorm:"column:user_id"`
+	Verse     int       `json:"verse" gorm:"column:verse"` // verse index in song text
+	Line      int       `json:"line" gorm:"column:line"`   // line index in verse
+	Start     int       `json:"start" gorm:"column:start_char"`
+	End       int       `json:"end" gorm:"column:end_char"`
+	Quote     string    `json:"quote" gorm:"column:quote"` // annotated text, used to re-anchor after edits
+	Body      string    `json:"body" gorm:"column:body"`
+	Votes     int       `json:"votes" gorm:"column:votes;default:0"`
+	Orphaned  bool      `json:"orphaned" gorm:"column:orphaned;default:false"` // quote not found after text edit
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// AnnotationVote is one user's up or down vote
+type AnnotationVote struct {
+	ID           int    `json:"-" gorm:"primaryKey"`
+	AnnotationID int    `json:"annotationId" gorm:"column:annotation_id;uniqueIndex:idx_vote_user_annotation"`
+	UserID       string `json:"userId" gorm:"column:user_id;uniqueIndex:idx_vote_user_annotation"`
+	Value        int    `json:"value" gorm:"column:value"` // +1 or -1
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// AudioAsset is the audio file of a song, the content is kept in the blob store under Key
+type AudioAsset struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	SongID      int       `json:"songId" gorm:"column:song_id;uniqueIndex"`
+	Key         string    `json:"-" gorm:"column:key;index"` // by content hash, equal files share a blob
+	ContentType string    `json:"contentType" gorm:"column:content_type"`
+	Size        int64     `json:"size" gorm:"column:size"`
+	SHA256      string    `json:"sha256" gorm:"column:sha256"`
+	FileName    string    `json:"fileName,omitempty" gorm:"column:file_name"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// Image is an uploaded picture, one row per content; the original and its
+// thumbnails are kept in the blob store by SHA256
+type Image struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	SHA256      string    `json:"sha256" gorm:"column:sha256;uniqueIndex"`
+	ContentType string    `json:"contentType" gorm:"column:content_type"`
+	Width       int       `json:"width" gorm:"column:width"`
+	Height      int       `json:"height" gorm:"column:height"`
+	Size        int64     `json:"size" gorm:"column:size"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// SongCover is the cover of one song
+type SongCover struct {
+	SongID    int       `json:"songId" gorm:"column:song_id;primaryKey;autoIncrement:false"`
+	ImageID   int       `json:"imageId" gorm:"column:image_id;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// AlbumCover is the cover of songs of an album without their own cover
+type AlbumCover struct {
+	AlbumKey  string    `json:"-" gorm:"column:album_key;primaryKey"` // dedup.Key(group, album)
+	Group     string    `json:"group" gorm:"column:group_name"`
+	Album     string    `json:"album" gorm:"column:album"`
+	ImageID   int       `json:"imageId" gorm:"column:image_id;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// Cover is image URLs of a song cover in responses
+type Cover struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"` // longest side in pixels - URL
+	FromAlbum  bool              `json:"fromAlbum"`  // the song has no own cover, this is the album one
+}
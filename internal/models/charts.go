@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// ChartEntry is one materialized position of a chart
+type ChartEntry struct {
+	ID         int       `json:"-" gorm:"primaryKey"`
+	Chart      string    `json:"-" gorm:"column:chart;index:idx_chart_lookup"`
+	Period     string    `json:"-" gorm:"column:period;index:idx_chart_lookup"`
+	Genre      string    `json:"-" gorm:"column:genre;index:idx_chart_lookup"`
+	Rank       int       `json:"rank" gorm:"column:rank"`
+	SongID     int       `json:"songId,omitempty" gorm:"column:song_id"`
+	Song       string    `json:"song,omitempty" gorm:"column:song"`
+	Group      string    `json:"group" gorm:"column:group_name"`
+	Score      float64   `json:"score" gorm:"column:score"`
+	ComputedAt time.Time `json:"-" gorm:"column:computed_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// EnrichmentCacheEntry is a persisted upstream answer, Found false caches "not found"
+type EnrichmentCacheEntry struct {
+	Key         string    `json:"key" gorm:"primaryKey;column:key"` // normalized group and song
+	Group       string    `json:"group" gorm:"column:group_name"`
+	Song        string    `json:"song" gorm:"column:song"`
+	Found       bool      `json:"found" gorm:"column:found"`
+	ReleaseDate time.Time `json:"releaseDate" gorm:"column:release_date"`
+	Precision   string    `json:"releaseDatePrecision" gorm:"column:release_date_precision"`
+	Text        string    `json:"text" gorm:"column:text"`
+	Link        string    `json:"link" gorm:"column:link"`
+	Source      string    `json:"source" gorm:"column:source"`
+	ExpiresAt   time.Time `json:"expiresAt" gorm:"column:expires_at;index"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at"`
+}
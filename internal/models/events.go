@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Play is a single listen of a song
+type Play struct {
+	ID       int       `json:"id" gorm:"primaryKey"`
+	SongID   int       `json:"songId" gorm:"column:song_id;index"`
+	UserID   string    `json:"userId" gorm:"column:user_id"`
+	PlayedAt time.Time `json:"playedAt" gorm:"column:played_at;index;autoCreateTime"`
+}
+
+// Favorite marks a song as liked by a user
+type Favorite struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	SongID    int       `json:"songId" gorm:"column:song_id;uniqueIndex:idx_favorite_user_song"`
+	UserID    string    `json:"userId" gorm:"column:user_id;uniqueIndex:idx_favorite_user_song"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;index;autoCreateTime"`
+}
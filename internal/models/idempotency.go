@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// idempotency record state
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyDone       = "done"
+)
+
+// IdempotencyRecord is the first response to a request with an Idempotency-Key
+type IdempotencyRecord struct {
+	ID          int       `gorm:"primaryKey"`
+	Caller      string    `gorm:"column:caller;uniqueIndex:idx_idempotency_caller_key"`
+	Key         string    `gorm:"column:key;uniqueIndex:idx_idempotency_caller_key"`
+	RequestHash string    `gorm:"column:request_hash"` // sha256 of method, URL and body
+	State       string    `gorm:"column:state"`        // processing, done
+	LockedUntil time.Time `gorm:"column:locked_until"` // lease of a processing request, renewed while it runs
+	Status      int       `gorm:"column:status"`
+	Headers     string    `gorm:"column:headers"` // JSON of http.Header
+	Body        []byte    `gorm:"column:body"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;index"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// job status
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// EnrichmentJob fetches song info for a pending song in background
+type EnrichmentJob struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	SongID    int       `json:"songId" gorm:"column:song_id;index"`
+	Status    string    `json:"status" gorm:"column:status;index"`           // queued, running, done, failed
+	Manual    bool      `json:"manual" gorm:"column:manual;default:false"`   // keep client fields, merge by precedence policy
+	Refresh   bool      `json:"refresh" gorm:"column:refresh;default:false"` // re-enrichment of a stored song
+	Attempts  int       `json:"attempts" gorm:"column:attempts"`
+	LastError string    `json:"lastError,omitempty" gorm:"column:last_error"`
+	NextRunAt time.Time `json:"nextRunAt" gorm:"column:next_run_at;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"music_library_api/internal/links"
+	"time"
+)
+
+// link health, set by the link checker
+const (
+	LinkStatusUnchecked = "unchecked"
+	LinkStatusOK        = "ok"
+	LinkStatusBroken    = "broken"
+)
+
+// SongLink is an external page of a song, URL is canonical (links.Parse)
+type SongLink struct {
+	ID            int        `json:"id" gorm:"primaryKey"`
+	SongID        int        `json:"songId" gorm:"column:song_id;uniqueIndex:idx_song_link_url"`
+	Platform      string     `json:"platform" gorm:"column:platform"` // youtube, spotify, applemusic, bandcamp, soundcloud, other
+	URL           string     `json:"url" gorm:"column:url;uniqueIndex:idx_song_link_url"`
+	ExternalID    string     `json:"externalId,omitempty" gorm:"column:external_id"`
+	EmbedURL      string     `json:"embedUrl,omitempty" gorm:"column:embed_url"`
+	Status        string     `json:"status" gorm:"column:status;default:unchecked;index"` // unchecked, ok, broken
+	HTTPStatus    int        `json:"httpStatus,omitempty" gorm:"column:http_status"`      // of the last probe, 0 if no response
+	CheckError    string     `json:"checkError,omitempty" gorm:"column:check_error"`
+	FailureStreak int        `json:"failureStreak" gorm:"column:failure_streak;default:0"` // failed probes in a row
+	CheckedAt     *time.Time `json:"checkedAt" gorm:"column:checked_at;index"`
+	LastOKAt      *time.Time `json:"lastOkAt,omitempty" gorm:"column:last_ok_at"`
+	CreatedAt     time.Time  `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// NewSongLink makes the row of a validated link
+func NewSongLink(songId int, link links.Link) SongLink {
+	return SongLink{
+		SongID:     songId,
+		Platform:   link.Platform,
+		URL:        link.URL,
+		ExternalID: link.ExternalID,
+		EmbedURL:   link.EmbedURL,
+		Status:     LinkStatusUnchecked,
+	}
+}
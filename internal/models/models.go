@@ -1,16 +1,92 @@
 package models
 
 import (
+	"encoding/json"
+	"music_library_api/internal/dedup"
+	"music_library_api/internal/links"
+	"music_library_api/internal/releasedate"
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// song enrichment status
+const (
+	SongStatusPending  = "pending"
+	SongStatusEnriched = "enriched"
+	SongStatusFailed   = "failed"
+	SongStatusManual   = "manual" // client data only, upstream has no info
 )
 
 type Song struct {
-	ID          int       `json:"id" gorm:"primaryKey"`
-	Group       string    `json:"group" gorm:"column:group_name"`
-	Song        string    `json:"song" gorm:"column:song"`
-	ReleaseDate time.Time `json:"releaseDate" gorm:"column:release_date"`
-	Text        string    `json:"text" gorm:"column:text"`
-	Link        string    `json:"link" gorm:"column:link"`
-	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt   time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+	ID                   int        `json:"id" gorm:"primaryKey"`
+	Group                string     `json:"group" gorm:"column:group_name"`
+	Song                 string     `json:"song" gorm:"column:song"`
+	Genre                string     `json:"genre" gorm:"column:genre"`
+	Album                string     `json:"album" gorm:"column:album"`
+	ReleaseDate          time.Time  `json:"releaseDate" gorm:"column:release_date" swaggertype:"string" example:"1991-09"`
+	ReleaseDatePrecision string     `json:"releaseDatePrecision" gorm:"column:release_date_precision;default:day"` // year, month, day
+	Text                 string     `json:"text" gorm:"column:text"`
+	Link                 string     `json:"link" gorm:"column:link"`                      // legacy, one of Links, YouTube preferred
+	DedupKey             *string    `json:"-" gorm:"column:dedup_key;uniqueIndex"`        // dedup.Key, NULL for duplicates stored before the index
+	Status               string     `json:"status" gorm:"column:status;default:enriched"` // pending, enriched, failed, manual
+	EnrichedAt           *time.Time `json:"enrichedAt" gorm:"column:enriched_at"`         // last successful fetch from upstream
+	RefreshAttemptedAt   *time.Time `json:"-" gorm:"column:refresh_attempted_at"`         // last re-enrichment attempt, failed ones included
+	CreatedAt            time.Time  `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt            time.Time  `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+
+	// review aggregates, filled by endpoints
+	RatingAvg   float64 `json:"ratingAvg" gorm:"-"`
+	RatingCount int     `json:"ratingCount" gorm:"-"`
+
+	// cover image URLs, filled by endpoints
+	Cover *Cover `json:"cover,omitempty" gorm:"-"`
+
+	// external links by platform, filled by endpoints
+	Links []SongLink `json:"links,omitempty" gorm:"-"`
+}
+
+// BeforeSave keeps the uniqueness key in sync with group and song,
+// old duplicates without a key are left alone until they are merged
+func (s *Song) BeforeSave(tx *gorm.DB) error {
+	if s.ID == 0 || s.DedupKey != nil {
+		key := dedup.Key(s.Group, s.Song)
+		s.DedupKey = &key
+	}
+	if link, err := links.Parse(s.Link, ""); err == nil {
+		s.Link = link.URL
+	}
+	return nil
+}
+
+// AfterSave adds the legacy link to the links of the song, so old clients setting
+// link keep the collection filled; a link that does not validate stays legacy only
+func (s *Song) AfterSave(tx *gorm.DB) error {
+	if s.ID == 0 {
+		return nil
+	}
+	return AddLegacyLink(tx, s.ID, s.Link)
+}
+
+// AddLegacyLink adds a legacy link value to the links of the song unless it is there already
+func AddLegacyLink(tx *gorm.DB, songId int, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	link, err := links.Parse(raw, "")
+	if err != nil {
+		return nil
+	}
+	songLink := NewSongLink(songId, link)
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&songLink).Error
+}
+
+// MarshalJSON renders releaseDate at its precision, so a year-only date is "1991", not "1991-01-01"
+func (s Song) MarshalJSON() ([]byte, error) {
+	type song Song // without MarshalJSON
+	return json.Marshal(struct {
+		song
+		ReleaseDate string `json:"releaseDate"`
+	}{song(s), releasedate.Format(s.ReleaseDate, s.ReleaseDatePrecision)})
 }
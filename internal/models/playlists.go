@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Playlist is an ordered list of songs
+type Playlist struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"column:name"`
+	UserID    string    `json:"userId" gorm:"column:user_id;index"` // owner, empty if created without X-User-Id
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// PlaylistEntry is a song at a position of a playlist, a song may repeat
+type PlaylistEntry struct {
+	ID         int `json:"id" gorm:"primaryKey"`
+	PlaylistID int `json:"playlistId" gorm:"column:playlist_id;index:idx_playlist_position"`
+	Position   int `json:"position" gorm:"column:position;index:idx_playlist_position"` // from 0
+	SongID     int `json:"songId" gorm:"column:song_id;index"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// FieldProvenance tells where the current value of a song field came from
+type FieldProvenance struct {
+	ID        int       `json:"-" gorm:"primaryKey"`
+	SongID    int       `json:"songId" gorm:"column:song_id;uniqueIndex:idx_provenance_song_field"`
+	Field     string    `json:"field" gorm:"column:field;uniqueIndex:idx_provenance_song_field"` // releaseDate, text, link
+	Source    string    `json:"source" gorm:"column:source"`                                     // client, edit or provider name
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
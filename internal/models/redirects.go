@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// SongRedirect points the ID of a merged away song to the song that replaced it
+type SongRedirect struct {
+	FromID    int       `json:"fromId" gorm:"column:from_id;primaryKey;autoIncrement:false"`
+	ToID      int       `json:"toId" gorm:"column:to_id;index"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// relation types, "A cover-of B" reads from A to B
+const (
+	RelationCoverOf         = "cover-of"
+	RelationRemixOf         = "remix-of"
+	RelationLiveVersionOf   = "live-version-of"
+	RelationSamples         = "samples"
+	RelationSameComposition = "same-composition" // symmetric, stored once in either direction
+)
+
+var RelationTypes = []string{RelationCoverOf, RelationRemixOf, RelationLiveVersionOf, RelationSamples, RelationSameComposition}
+
+// SongRelation is a typed directed edge between two songs
+type SongRelation struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	FromSongID int       `json:"fromSongId" gorm:"column:from_song_id;uniqueIndex:idx_song_relation"`
+	ToSongID   int       `json:"toSongId" gorm:"column:to_song_id;uniqueIndex:idx_song_relation;index"`
+	Type       string    `json:"type" gorm:"column:type;uniqueIndex:idx_song_relation"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
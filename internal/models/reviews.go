@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Review is a user's star rating of a song with optional text
+type Review struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	SongID    int       `json:"songId" gorm:"column:song_id;uniqueIndex:idx_review_user_song"`
+	UserID    string    `json:"userId" gorm:"column:user_id;uniqueIndex:idx_review_user_song"`
+	Rating    int       `json:"rating" gorm:"column:rating"` // 1..5
+	Text      string    `json:"text" gorm:"column:text"`
+	Hidden    bool      `json:"hidden" gorm:"column:hidden;default:false"` // hidden by moderator
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// SyncedLyrics is time-synced LRC of a song, stored next to plain Song.Text
+type SyncedLyrics struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	SongID    int       `json:"songId" gorm:"column:song_id;uniqueIndex"`
+	Raw       string    `json:"raw" gorm:"column:raw"` // validated LRC as uploaded
+	Enhanced  bool      `json:"enhanced" gorm:"column:enhanced"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
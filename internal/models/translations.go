@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Translation is song lyrics in another language
+type Translation struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	SongID     int       `json:"songId" gorm:"column:song_id;uniqueIndex:idx_translation_song_lang"`
+	Lang       string    `json:"lang" gorm:"column:lang;uniqueIndex:idx_translation_song_lang"` // BCP-47 tag, e.g. ru, pt-BR
+	Text       string    `json:"text" gorm:"column:text"`
+	Translator string    `json:"translator" gorm:"column:translator"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
@@ -0,0 +1,66 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseM3U reads plain and extended M3U, #EXTINF gives duration and "Artist - Title"
+func parseM3U(r io.Reader) (string, []Entry, error) {
+	var title string
+	var entries []Entry
+	var pending *Entry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			duration, name, _ := strings.Cut(info, ",")
+			// attributes like tvg-id="x" may follow the duration
+			duration, _, _ = strings.Cut(strings.TrimSpace(duration), " ")
+			seconds, err := strconv.Atoi(duration)
+			if err != nil {
+				seconds = -1
+			}
+			artist, title := splitDisplay(name)
+			pending = &Entry{Artist: artist, Title: title, Duration: seconds}
+		case strings.HasPrefix(line, "#"):
+		default:
+			entry := Entry{Location: line, Duration: -1}
+			if pending != nil {
+				entry.Artist, entry.Title, entry.Duration = pending.Artist, pending.Title, pending.Duration
+				pending = nil
+			}
+			if entry.Title == "" {
+				entry.Artist, entry.Title = fromLocation(line)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return title, entries, scanner.Err()
+}
+
+func writeM3U(w io.Writer, title string, entries []Entry) (int, error) {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	if title != "" {
+		fmt.Fprintf(bw, "#PLAYLIST:%s\n", title)
+	}
+	skipped := 0
+	for _, e := range entries {
+		if e.Location == "" {
+			skipped++
+			continue
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n%s\n", e.Duration, display(e), e.Location)
+	}
+	return skipped, bw.Flush()
+}
@@ -0,0 +1,81 @@
+package playlist
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// supported formats
+const (
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+	FormatPLS  = "pls"
+)
+
+// ContentTypes of the formats
+var ContentTypes = map[string]string{
+	FormatM3U8: "audio/x-mpegurl; charset=utf-8",
+	FormatXSPF: "application/xspf+xml",
+	FormatPLS:  "audio/x-scpls",
+}
+
+// Entry is one track of a playlist file, Duration is in seconds, -1 if unknown
+type Entry struct {
+	Artist   string `json:"artist"`
+	Title    string `json:"title"`
+	Location string `json:"location"`
+	Duration int    `json:"duration"`
+}
+
+// Parse reads a playlist file of the format, returns its title if the format has one
+func Parse(format string, r io.Reader) (string, []Entry, error) {
+	switch format {
+	case FormatM3U8:
+		return parseM3U(r)
+	case FormatXSPF:
+		return parseXSPF(r)
+	case FormatPLS:
+		entries, err := parsePLS(r)
+		return "", entries, err
+	}
+	return "", nil, fmt.Errorf("unknown playlist format %q", format)
+}
+
+// Write renders entries in the format, entries without location are skipped
+// where the format requires it (M3U8, PLS); returns the number of skipped entries
+func Write(format string, w io.Writer, title string, entries []Entry) (int, error) {
+	switch format {
+	case FormatM3U8:
+		return writeM3U(w, title, entries)
+	case FormatXSPF:
+		return 0, writeXSPF(w, title, entries)
+	case FormatPLS:
+		return writePLS(w, entries)
+	}
+	return 0, fmt.Errorf("unknown playlist format %q", format)
+}
+
+// splitDisplay reads "Artist - Title", a string without separator is the title
+func splitDisplay(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	if artist, title, ok := strings.Cut(value, " - "); ok {
+		return strings.TrimSpace(artist), strings.TrimSpace(title)
+	}
+	return "", value
+}
+
+func display(e Entry) string {
+	if e.Artist == "" {
+		return e.Title
+	}
+	return e.Artist + " - " + e.Title
+}
+
+// fromLocation guesses artist and title from a file name like "Muse - Uprising.mp3"
+func fromLocation(location string) (string, string) {
+	name := path.Base(strings.ReplaceAll(location, "\\", "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return splitDisplay(name)
+}
@@ -0,0 +1,85 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parsePLS reads [playlist] with FileN, TitleN and LengthN keys
+func parsePLS(r io.Reader) ([]Entry, error) {
+	byIndex := map[int]*Entry{}
+	entry := func(n int) *Entry {
+		if byIndex[n] == nil {
+			byIndex[n] = &Entry{Duration: -1}
+		}
+		return byIndex[n]
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue // [playlist] and junk
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		for _, prefix := range []string{"file", "title", "length"} {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			n, err := strconv.Atoi(key[len(prefix):])
+			if err != nil {
+				break
+			}
+			switch prefix {
+			case "file":
+				entry(n).Location = value
+			case "title":
+				entry(n).Artist, entry(n).Title = splitDisplay(value)
+			case "length":
+				if seconds, err := strconv.Atoi(value); err == nil {
+					entry(n).Duration = seconds
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0, len(byIndex))
+	for n := range byIndex {
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+	entries := make([]Entry, 0, len(indexes))
+	for _, n := range indexes {
+		e := *byIndex[n]
+		if e.Title == "" && e.Location != "" {
+			e.Artist, e.Title = fromLocation(e.Location)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func writePLS(w io.Writer, entries []Entry) (int, error) {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[playlist]")
+	n, skipped := 0, 0
+	for _, e := range entries {
+		if e.Location == "" {
+			skipped++
+			continue
+		}
+		n++
+		fmt.Fprintf(bw, "File%d=%s\nTitle%d=%s\nLength%d=%d\n", n, e.Location, n, display(e), n, e.Duration)
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\nVersion=2\n", n)
+	return skipped, bw.Flush()
+}
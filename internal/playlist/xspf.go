@@ -0,0 +1,65 @@
+package playlist
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Title    string `xml:"title,omitempty"`
+	Duration int    `xml:"duration,omitempty"` // milliseconds
+}
+
+func parseXSPF(r io.Reader) (string, []Entry, error) {
+	var doc xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return "", nil, err
+	}
+	entries := make([]Entry, 0, len(doc.Tracks))
+	for _, t := range doc.Tracks {
+		e := Entry{
+			Artist:   strings.TrimSpace(t.Creator),
+			Title:    strings.TrimSpace(t.Title),
+			Location: strings.TrimSpace(t.Location),
+			Duration: -1,
+		}
+		if t.Duration > 0 {
+			e.Duration = t.Duration / 1000
+		}
+		if e.Title == "" && e.Location != "" {
+			e.Artist, e.Title = fromLocation(e.Location)
+		}
+		entries = append(entries, e)
+	}
+	return strings.TrimSpace(doc.Title), entries, nil
+}
+
+func writeXSPF(w io.Writer, title string, entries []Entry) error {
+	doc := xspfPlaylist{Version: "1", Title: title, Tracks: make([]xspfTrack, len(entries))}
+	for i, e := range entries {
+		doc.Tracks[i] = xspfTrack{Location: e.Location, Creator: e.Artist, Title: e.Title}
+		if e.Duration > 0 {
+			doc.Tracks[i].Duration = e.Duration * 1000
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,87 @@
+package releasedate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// precision of a release date, partial dates are stored as the first day of the period
+const (
+	Year  = "year"
+	Month = "month"
+	Day   = "day"
+)
+
+type layout struct {
+	value     string
+	precision string
+}
+
+// layouts tried in order, ambiguous numeric forms like 01/02/2006 are not accepted
+var layouts = []layout{
+	{"2006-01-02", Day},
+	{"02.01.2006", Day},
+	{"2006/01/02", Day},
+	{"2006.01.02", Day},
+	{time.RFC3339Nano, Day},
+	{time.RFC3339, Day},
+	{"2006-01-02T15:04:05", Day},
+	{"2006-01-02 15:04:05", Day},
+	{"January 2, 2006", Day},
+	{"Jan 2, 2006", Day},
+	{"2 January 2006", Day},
+	{"2 Jan 2006", Day},
+	{"20060102", Day},
+	{"2006-01", Month},
+	{"2006/01", Month},
+	{"01.2006", Month},
+	{"01/2006", Month},
+	{"January 2006", Month},
+	{"Jan 2006", Month},
+	{"2006", Year},
+}
+
+// Parse reads a full or partial date and returns it with its precision
+func Parse(value string) (time.Time, string, error) {
+	value = strings.Join(strings.Fields(value), " ")
+	value = strings.Replace(value, "Sept ", "Sep ", 1)
+	for _, l := range layouts {
+		t, err := time.Parse(l.value, value)
+		if err != nil {
+			continue
+		}
+		// timestamps keep their calendar day, not the UTC one
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return t, l.precision, nil
+	}
+	return time.Time{}, "", fmt.Errorf("unknown date format %q, use YYYY-MM-DD, YYYY-MM or YYYY", value)
+}
+
+// Format renders a date at its precision: 1991, 1991-09 or 1991-09-24, zero date is empty
+func Format(t time.Time, precision string) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch precision {
+	case Year:
+		return t.Format("2006")
+	case Month:
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
+
+var rank = map[string]int{Year: 1, Month: 2, Day: 3, "": 3}
+
+// Refines reports whether date b is more precise than a and falls into a's period,
+// e.g. 1991-09-24 refines 1991 and 1991-09, but not 1992
+func Refines(a time.Time, aPrecision string, b time.Time, bPrecision string) bool {
+	if a.IsZero() || b.IsZero() || rank[bPrecision] <= rank[aPrecision] {
+		return false
+	}
+	if aPrecision == Year {
+		return a.Year() == b.Year()
+	}
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
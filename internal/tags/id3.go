@@ -0,0 +1,336 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const maxID3Size = 64 << 20 // covers of large tags are a few MB
+
+// readID3v2 reads the tag that starts with header, size is the tag length with header and footer
+func readID3v2(r io.Reader, header []byte) (*Tags, int64, error) {
+	version, flags := header[3], header[5]
+	size := syncsafe(header[6:10])
+	total := int64(10 + size)
+	if flags&0x10 != 0 {
+		total += 10 // footer
+	}
+	if version < 2 || version > 4 {
+		return nil, 0, errorf("unsupported ID3v2.%d", version)
+	}
+	if size > maxID3Size {
+		return nil, 0, errorf("ID3v2 tag of %d bytes is too large", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, errorf("truncated ID3v2 tag: %v", err)
+	}
+
+	// v2.4 unsynchronises frames one by one
+	if flags&0x80 != 0 && version < 4 {
+		body = unsynchronise(body)
+	}
+	if flags&0x40 != 0 && version > 2 {
+		if len(body) < 4 {
+			return nil, 0, errorf("invalid ID3v2 extended header")
+		}
+		var skip int
+		if version == 3 {
+			skip = 4 + int(binary.BigEndian.Uint32(body))
+		} else {
+			skip = syncsafe(body[:4])
+		}
+		if skip < 0 || skip > len(body) {
+			return nil, 0, errorf("invalid ID3v2 extended header")
+		}
+		body = body[skip:]
+	}
+
+	t := &Tags{Format: FormatMP3, Container: "ID3v2." + strconv.Itoa(int(version))}
+	var ddmm string // TDAT of v2.3, day and month for TYER
+	for len(body) > 0 {
+		id, data, rest, ok := nextFrame(body, version)
+		if !ok {
+			break // padding or broken frame, keep what was read
+		}
+		body = rest
+		if data == nil {
+			continue
+		}
+		if id == "TDAT" {
+			ddmm = textFrame(data)
+			continue
+		}
+		setFrame(t, id, data)
+	}
+	if len(t.Date) == 4 && len(ddmm) == 4 {
+		t.Date += "-" + ddmm[2:] + "-" + ddmm[:2]
+	}
+	return t, total, nil
+}
+
+// nextFrame splits the first frame, data is nil for frames that can not be read (compressed, encrypted)
+func nextFrame(body []byte, version byte) (id string, data, rest []byte, ok bool) {
+	if version == 2 {
+		if len(body) < 6 || body[0] == 0 {
+			return "", nil, nil, false
+		}
+		size := int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		if 6+size > len(body) {
+			return "", nil, nil, false
+		}
+		return v22Frames[string(body[:3])], body[6 : 6+size], body[6+size:], true
+	}
+
+	if len(body) < 10 || body[0] == 0 {
+		return "", nil, nil, false
+	}
+	id = string(body[:4])
+	size := int(binary.BigEndian.Uint32(body[4:8]))
+	if version == 4 {
+		size = syncsafe(body[4:8])
+	}
+	if size < 0 || 10+size > len(body) {
+		return "", nil, nil, false
+	}
+	data, rest = body[10:10+size], body[10+size:]
+	flags := binary.BigEndian.Uint16(body[8:10])
+
+	if version == 3 {
+		if flags&0x00C0 != 0 { // compressed or encrypted
+			return id, nil, rest, true
+		}
+		if flags&0x0020 != 0 && len(data) > 0 { // group byte
+			data = data[1:]
+		}
+		return id, data, rest, true
+	}
+	if flags&0x000C != 0 { // compressed or encrypted
+		return id, nil, rest, true
+	}
+	if flags&0x0040 != 0 && len(data) > 0 { // group byte
+		data = data[1:]
+	}
+	if flags&0x0001 != 0 && len(data) >= 4 { // data length indicator
+		data = data[4:]
+	}
+	if flags&0x0002 != 0 {
+		data = unsynchronise(data)
+	}
+	return id, data, rest, true
+}
+
+// v22Frames maps three letter frames of ID3v2.2 to their later names
+var v22Frames = map[string]string{
+	"TT2": "TIT2", "TP1": "TPE1", "TP2": "TPE2", "TAL": "TALB", "TCO": "TCON",
+	"TYE": "TYER", "TRK": "TRCK", "ULT": "USLT", "PIC": "PIC",
+}
+
+func setFrame(t *Tags, id string, data []byte) {
+	switch id {
+	case "TIT2":
+		t.Title = textFrame(data)
+	case "TPE1":
+		t.Artist = textFrame(data)
+	case "TPE2":
+		t.AlbumArtist = textFrame(data)
+	case "TALB":
+		t.Album = textFrame(data)
+	case "TCON":
+		t.Genre = id3Genre(textFrame(data))
+	case "TRCK":
+		t.Track = textFrame(data)
+	case "TDRC", "TDRL":
+		if t.Date == "" || len(t.Date) == 4 {
+			t.Date = textFrame(data)
+		}
+	case "TYER":
+		if t.Date == "" {
+			t.Date = textFrame(data)
+		}
+	case "USLT":
+		if len(data) > 4 && t.Lyrics == "" {
+			enc := data[0]
+			_, text := splitText(enc, data[4:]) // language, description
+			t.Lyrics = decodeText(enc, text)
+		}
+	case "APIC":
+		if p, ok := apicFrame(data); ok {
+			t.Pictures = append(t.Pictures, p)
+		}
+	case "PIC":
+		if len(data) > 5 {
+			enc, format := data[0], strings.ToLower(string(data[1:4]))
+			desc, picture := splitText(enc, data[5:])
+			t.Pictures = append(t.Pictures, Picture{MIMEType: "image/" + strings.Replace(format, "jpg", "jpeg", 1),
+				Type: int(data[4]), Description: decodeText(enc, desc), Data: picture})
+		}
+	}
+}
+
+func apicFrame(data []byte) (Picture, bool) {
+	if len(data) < 2 {
+		return Picture{}, false
+	}
+	enc := data[0]
+	end := bytes.IndexByte(data[1:], 0)
+	if end < 0 || 1+end+2 > len(data) {
+		return Picture{}, false
+	}
+	mimeType := string(data[1 : 1+end])
+	rest := data[1+end+1:]
+	desc, picture := splitText(enc, rest[1:])
+	if !strings.Contains(mimeType, "/") { // some writers store "jpg"
+		mimeType = "image/" + strings.Replace(strings.ToLower(mimeType), "jpg", "jpeg", 1)
+	}
+	return Picture{MIMEType: mimeType, Type: int(rest[0]), Description: decodeText(enc, desc), Data: picture}, true
+}
+
+// textFrame decodes a text frame, several values of v2.4 are joined with "/"
+func textFrame(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	text := decodeText(data[0], data[1:])
+	values := strings.Split(strings.TrimRight(text, "\x00"), "\x00")
+	return strings.Join(values, "/")
+}
+
+// splitText cuts a terminated string of the encoding from the rest
+func splitText(enc byte, data []byte) (text, rest []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[:i], data[i+2:]
+			}
+		}
+		return data, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return data[:i], data[i+1:]
+	}
+	return data, nil
+}
+
+// decodeText converts ISO-8859-1 (0), UTF-16 with BOM (1), UTF-16BE (2) or UTF-8 (3) to a string
+func decodeText(enc byte, data []byte) string {
+	switch enc {
+	case 0:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case 1, 2:
+		bigEndian := enc == 2
+		if len(data) >= 2 {
+			switch {
+			case data[0] == 0xFF && data[1] == 0xFE:
+				bigEndian, data = false, data[2:]
+			case data[0] == 0xFE && data[1] == 0xFF:
+				bigEndian, data = true, data[2:]
+			}
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(data[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(data[2*i:])
+			}
+		}
+		// a text of several values has a BOM per value
+		return strings.ReplaceAll(string(utf16.Decode(units)), "\ufeff", "")
+	}
+	return string(data)
+}
+
+// id3Genre resolves "(17)", "17" and "(17)Rock" to genre names of ID3v1
+func id3Genre(value string) string {
+	if strings.HasPrefix(value, "(") {
+		if end := strings.Index(value, ")"); end > 0 {
+			if rest := value[end+1:]; rest != "" {
+				return rest
+			}
+			value = value[1:end]
+		}
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n >= 0 && n < len(genres) {
+			return genres[n]
+		}
+		return ""
+	}
+	return value
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// unsynchronise drops the zero byte stuffed after every 0xFF
+func unsynchronise(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
+
+// readID3v1 reads the 128 byte tag at the end of the file, nil if there is none
+func readID3v1(r io.ReadSeeker) (*Tags, error) {
+	if _, err := r.Seek(-128, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	b := make([]byte, 128)
+	if _, err := io.ReadFull(r, b); err != nil || string(b[:3]) != "TAG" {
+		return nil, err
+	}
+	field := func(b []byte) string {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.TrimSpace(decodeText(0, b))
+	}
+	t := &Tags{
+		Format:    FormatMP3,
+		Container: "ID3v1",
+		Title:     field(b[3:33]),
+		Artist:    field(b[33:63]),
+		Album:     field(b[63:93]),
+		Date:      field(b[93:97]),
+	}
+	if b[125] == 0 && b[126] != 0 { // ID3v1.1 track number
+		t.Track = strconv.Itoa(int(b[126]))
+	}
+	if int(b[127]) < len(genres) {
+		t.Genre = genres[b[127]]
+	}
+	return t, nil
+}
+
+// genres of ID3v1 with Winamp extensions
+var genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz", "Metal",
+	"New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno", "Industrial",
+	"Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk",
+	"Fusion", "Trance", "Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic",
+	"Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta",
+	"Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave", "Psychedelic", "Rave", "Showtunes",
+	"Trailer", "Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebob", "Latin", "Revival", "Celtic", "Bluegrass",
+	"Avantgarde", "Gothic Rock", "Progressive Rock", "Psychedelic Rock", "Symphonic Rock", "Slow Rock", "Big Band", "Chorus", "Easy Listening", "Acoustic",
+	"Humour", "Speech", "Chanson", "Opera", "Chamber Music", "Sonata", "Symphony", "Booty Bass", "Primus", "Porn Groove",
+	"Satire", "Slow Jam", "Club", "Tango", "Samba", "Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle",
+	"Duet", "Punk Rock", "Drum Solo", "A capella", "Euro-House", "Dance Hall", "Goa", "Drum & Bass", "Club-House", "Hardcore",
+	"Terror", "Indie", "BritPop", "Negerpunk", "Polsk Punk", "Beat", "Christian Gangsta Rap", "Heavy Metal", "Black Metal", "Crossover",
+	"Contemporary Christian", "Christian Rock", "Merengue", "Salsa", "Thrash Metal", "Anime", "JPop", "Synthpop",
+}
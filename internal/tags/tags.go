@@ -0,0 +1,150 @@
+// Package tags reads metadata of audio files: ID3v1 and ID3v2 of MP3,
+// Vorbis comments of FLAC and Ogg (Vorbis, Opus)
+package tags
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formats
+const (
+	FormatMP3  = "mp3"
+	FormatFLAC = "flac"
+	FormatOgg  = "ogg"
+)
+
+// ErrUnknownFormat is returned for files that are not MP3, FLAC or Ogg
+var ErrUnknownFormat = errors.New("unknown audio format, expected MP3, FLAC or Ogg")
+
+// Picture is an embedded cover
+type Picture struct {
+	MIMEType    string `json:"mimeType"`
+	Type        int    `json:"type"` // 3 - front cover
+	Description string `json:"description,omitempty"`
+	Data        []byte `json:"-"`
+}
+
+// Tags found in a file, empty fields are missing
+type Tags struct {
+	Format      string    `json:"format"`
+	Container   string    `json:"container,omitempty"` // ID3v2.4, ID3v1, Vorbis comment ...
+	Title       string    `json:"title,omitempty"`
+	Artist      string    `json:"artist,omitempty"`
+	AlbumArtist string    `json:"albumArtist,omitempty"`
+	Album       string    `json:"album,omitempty"`
+	Genre       string    `json:"genre,omitempty"`
+	Date        string    `json:"date,omitempty"` // as written: 1991, 1991-09-24 ...
+	Track       string    `json:"track,omitempty"`
+	Lyrics      string    `json:"lyrics,omitempty"`
+	Pictures    []Picture `json:"pictures,omitempty"`
+}
+
+// Cover returns the front cover or the first picture, nil if there are none
+func (t *Tags) Cover() *Picture {
+	for i := range t.Pictures {
+		if t.Pictures[i].Type == 3 {
+			return &t.Pictures[i]
+		}
+	}
+	if len(t.Pictures) > 0 {
+		return &t.Pictures[0]
+	}
+	return nil
+}
+
+// fill sets empty fields from another tag, e.g. ID3v1 after ID3v2
+func (t *Tags) fill(o *Tags) {
+	set := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	set(&t.Title, o.Title)
+	set(&t.Artist, o.Artist)
+	set(&t.AlbumArtist, o.AlbumArtist)
+	set(&t.Album, o.Album)
+	set(&t.Genre, o.Genre)
+	set(&t.Date, o.Date)
+	set(&t.Track, o.Track)
+	set(&t.Lyrics, o.Lyrics)
+	t.Pictures = append(t.Pictures, o.Pictures...)
+}
+
+// Read detects the format by content and reads its tags
+func Read(r io.ReadSeeker) (*Tags, error) {
+	head := make([]byte, 10)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	// FLAC may start with an ID3v2 tag too
+	var id3 *Tags
+	offset := int64(0)
+	if bytes.HasPrefix(head, []byte("ID3")) {
+		tag, size, err := readID3v2(r, head)
+		if err != nil {
+			return nil, err
+		}
+		id3, offset = tag, size
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, head[:4]); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+
+	var t *Tags
+	var err error
+	switch {
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		if _, err = r.Seek(offset+4, io.SeekStart); err == nil {
+			t, err = readFLAC(r)
+		}
+	case bytes.HasPrefix(head, []byte("OggS")):
+		if _, err = r.Seek(offset, io.SeekStart); err == nil {
+			t, err = readOgg(r)
+		}
+	case id3 != nil || isMPEGFrame(head):
+		t = &Tags{Format: FormatMP3}
+		if id3 != nil {
+			t = id3
+		}
+		if v1, _ := readID3v1(r); v1 != nil {
+			if t.Container == "" {
+				t.Container = v1.Container
+			}
+			t.fill(v1)
+		}
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if id3 != nil && t != id3 {
+		t.fill(id3)
+	}
+	trim(t)
+	return t, nil
+}
+
+// isMPEGFrame checks for an MPEG audio frame sync of a file without ID3v2
+func isMPEGFrame(head []byte) bool {
+	return head[0] == 0xFF && head[1]&0xE0 == 0xE0
+}
+
+func trim(t *Tags) {
+	for _, s := range []*string{&t.Title, &t.Artist, &t.AlbumArtist, &t.Album, &t.Genre, &t.Date, &t.Track} {
+		*s = strings.TrimSpace(strings.Trim(*s, "\x00"))
+	}
+	t.Lyrics = strings.TrimSpace(strings.ReplaceAll(t.Lyrics, "\r\n", "\n"))
+}
+
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid tags: "+format, args...)
+}
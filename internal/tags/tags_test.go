@@ -0,0 +1,202 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// id3v2 builds a tag of the version from frames, each one is id and data
+func id3v2(version, flags byte, body []byte) []byte {
+	size := len(body)
+	header := []byte{'I', 'D', '3', version, 0, flags,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(header, body...)
+}
+
+func frame(version byte, id string, data []byte) []byte {
+	if version == 2 {
+		n := len(data)
+		return append([]byte{id[0], id[1], id[2], byte(n >> 16), byte(n >> 8), byte(n)}, data...)
+	}
+	head := make([]byte, 10)
+	copy(head, id)
+	if version == 4 {
+		n := len(data)
+		head[4], head[5], head[6], head[7] = byte(n>>21&0x7F), byte(n>>14&0x7F), byte(n>>7&0x7F), byte(n&0x7F)
+	} else {
+		binary.BigEndian.PutUint32(head[4:8], uint32(len(data)))
+	}
+	return append(head, data...)
+}
+
+// text is a text frame in ISO-8859-1
+func text(value string) []byte {
+	return append([]byte{0}, value...)
+}
+
+func frames(version byte, list ...[]byte) []byte {
+	var body []byte
+	for i := 0; i+1 < len(list); i += 2 {
+		body = append(body, frame(version, string(list[i]), list[i+1])...)
+	}
+	return body
+}
+
+// mpegFrame is a frame sync with some audio after it
+var mpegFrame = append([]byte{0xFF, 0xFB, 0x90, 0x64}, make([]byte, 200)...)
+
+func id3v1(title, artist string, genre byte) []byte {
+	b := make([]byte, 128)
+	copy(b, "TAG")
+	copy(b[3:33], title)
+	copy(b[33:63], artist)
+	copy(b[93:97], "1991")
+	b[126] = 7
+	b[127] = genre
+	return b
+}
+
+func vorbisComment(fields ...string) []byte {
+	var b []byte
+	put := func(s string) {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	put("vendor")
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(fields)))
+	for _, f := range fields {
+		put(f)
+	}
+	return b
+}
+
+func flac(comment []byte) []byte {
+	b := []byte("fLaC")
+	b = append(b, 0, 0, 0, 34) // STREAMINFO
+	b = append(b, make([]byte, 34)...)
+	n := len(comment)
+	b = append(b, 0x80|4, byte(n>>16), byte(n>>8), byte(n))
+	return append(b, comment...)
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want Tags
+	}{
+		{
+			name: "ID3v2.3 with TDAT",
+			file: append(id3v2(3, 0, frames(3,
+				[]byte("TIT2"), text("Smells Like Teen Spirit"),
+				[]byte("TPE1"), text("Nirvana"),
+				[]byte("TCON"), text("(17)"),
+				[]byte("TYER"), text("1991"),
+				[]byte("TDAT"), text("2409"),
+			)), mpegFrame...),
+			want: Tags{Format: FormatMP3, Container: "ID3v2.3", Title: "Smells Like Teen Spirit",
+				Artist: "Nirvana", Genre: "Rock", Date: "1991-09-24"},
+		},
+		{
+			name: "ID3v2.4 UTF-8 with several values",
+			file: append(id3v2(4, 0, frames(4,
+				[]byte("TIT2"), append([]byte{3}, "Кино"...),
+				[]byte("TPE1"), append([]byte{3}, "A\x00B"...),
+				[]byte("TDRC"), text("1988-01"),
+			)), mpegFrame...),
+			want: Tags{Format: FormatMP3, Container: "ID3v2.4", Title: "Кино", Artist: "A/B", Date: "1988-01"},
+		},
+		{
+			name: "ID3v2.2",
+			file: append(id3v2(2, 0, frames(2,
+				[]byte("TT2"), text("Title"),
+				[]byte("TAL"), text("Album"),
+			)), mpegFrame...),
+			want: Tags{Format: FormatMP3, Container: "ID3v2.2", Title: "Title", Album: "Album"},
+		},
+		{
+			name: "ID3v2.3 extended header",
+			file: append(id3v2(3, 0x40, append([]byte{0, 0, 0, 6, 0, 0, 0, 0, 0, 0},
+				frames(3, []byte("TIT2"), text("Title"))...)), mpegFrame...),
+			want: Tags{Format: FormatMP3, Container: "ID3v2.3", Title: "Title"},
+		},
+		{
+			name: "ID3v1 fills missing fields",
+			file: append(append(id3v2(3, 0, frames(3, []byte("TIT2"), text("Title v2"))), mpegFrame...),
+				id3v1("Title v1", "Artist v1", 17)...),
+			want: Tags{Format: FormatMP3, Container: "ID3v2.3", Title: "Title v2", Artist: "Artist v1",
+				Genre: "Rock", Date: "1991", Track: "7"},
+		},
+		{
+			name: "ID3v1 only",
+			file: append(append([]byte{}, mpegFrame...), id3v1("Title", "Artist", 255)...),
+			want: Tags{Format: FormatMP3, Container: "ID3v1", Title: "Title", Artist: "Artist", Date: "1991", Track: "7"},
+		},
+		{
+			name: "FLAC",
+			file: flac(vorbisComment("TITLE=Title", "artist=A", "ARTIST=B", "DATE=2001", "LYRICS=line\r\nline")),
+			want: Tags{Format: FormatFLAC, Container: "Vorbis comment", Title: "Title", Artist: "A/B",
+				Date: "2001", Lyrics: "line\nline"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			got.Pictures = nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Read() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{"empty", nil, "unknown audio format"},
+		{"text", []byte("just some text file"), "unknown audio format"},
+		{"unsupported version", id3v2(5, 0, nil), "unsupported ID3v2.5"},
+		{"truncated tag", id3v2(3, 0, make([]byte, 20))[:15], "truncated ID3v2 tag"},
+		{"extended header flag without header v2.3", id3v2(3, 0x40, []byte{0, 0}), "invalid ID3v2 extended header"},
+		{"extended header flag without header v2.4", id3v2(4, 0x40, []byte{0}), "invalid ID3v2 extended header"},
+		{"extended header flag on empty tag", id3v2(3, 0x40, nil), "invalid ID3v2 extended header"},
+		{"extended header longer than tag", id3v2(3, 0x40, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0}), "invalid ID3v2 extended header"},
+		{"truncated FLAC", []byte("fLaC\x00\x00\x00\x22\x00\x00\x00\x00"), "truncated FLAC block"},
+		{"truncated Vorbis comment", flac([]byte{0xFF, 0, 0, 0}), "truncated Vorbis comment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Read() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Read(bytes.NewReader(nil)); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Read(empty) error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+// FuzzRead checks that no input makes Read panic
+func FuzzRead(f *testing.F) {
+	f.Add(append(id3v2(3, 0, frames(3, []byte("TIT2"), text("Title"))), mpegFrame...))
+	f.Add(append(id3v2(4, 0x40, []byte{0, 0, 0, 6, 1, 0}), mpegFrame...))
+	f.Add(id3v2(3, 0x40, []byte{0, 0}))
+	f.Add(id3v2(2, 0, frames(2, []byte("PIC"), []byte{0, 'J', 'P', 'G', 3, 0, 0xFF, 0xD8})))
+	f.Add(flac(vorbisComment("TITLE=Title", "METADATA_BLOCK_PICTURE=AAAAAw==")))
+	f.Add(append(append([]byte{}, mpegFrame...), id3v1("Title", "Artist", 17)...))
+	f.Add([]byte("OggS"))
+	f.Fuzz(func(t *testing.T, file []byte) {
+		Read(bytes.NewReader(file))
+	})
+}
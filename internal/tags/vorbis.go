@@ -0,0 +1,215 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+const maxBlockSize = 16 << 20 // FLAC metadata blocks are at most 16 MB by format
+
+// readFLAC reads metadata blocks after the "fLaC" marker
+func readFLAC(r io.Reader) (*Tags, error) {
+	t := &Tags{Format: FormatFLAC}
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, errorf("truncated FLAC metadata: %v", err)
+		}
+		last, kind := header[0]&0x80 != 0, header[0]&0x7F
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+
+		switch kind {
+		case 4, 6: // VORBIS_COMMENT, PICTURE
+			block := make([]byte, size)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, errorf("truncated FLAC block: %v", err)
+			}
+			if kind == 4 {
+				t.Container = "Vorbis comment"
+				if err := readComments(t, block); err != nil {
+					return nil, err
+				}
+			} else if p, ok := flacPicture(block); ok {
+				t.Pictures = append(t.Pictures, p)
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+				return nil, errorf("truncated FLAC block: %v", err)
+			}
+		}
+		if last || kind == 127 {
+			return t, nil
+		}
+	}
+}
+
+// readOgg reads the comment header of the first logical stream: Vorbis or Opus
+func readOgg(r io.Reader) (*Tags, error) {
+	t := &Tags{Format: FormatOgg}
+	var packet []byte
+	var serial uint32
+	packets := 0
+	header := make([]byte, 27)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, errorf("truncated Ogg stream: %v", err)
+		}
+		if string(header[:4]) != "OggS" {
+			return nil, errorf("invalid Ogg page")
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if packets == 0 && len(packet) == 0 {
+			serial = pageSerial
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return nil, errorf("truncated Ogg page: %v", err)
+		}
+		for _, size := range segments {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, errorf("truncated Ogg page: %v", err)
+			}
+			if pageSerial != serial {
+				continue // multiplexed stream
+			}
+			if len(packet)+len(data) > maxBlockSize {
+				return nil, errorf("Ogg comment header is too large")
+			}
+			packet = append(packet, data...)
+			if size == 255 {
+				continue // packet goes on
+			}
+			packets++
+			if packets == 2 {
+				return t, oggComments(t, packet)
+			}
+			packet = nil
+		}
+	}
+}
+
+func oggComments(t *Tags, packet []byte) error {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		t.Container = "Vorbis comment"
+		return readComments(t, packet[7:])
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		t.Container = "Opus comment"
+		return readComments(t, packet[8:])
+	}
+	return errorf("Ogg stream is neither Vorbis nor Opus")
+}
+
+// readComments reads a vendor string and KEY=value fields
+func readComments(t *Tags, data []byte) error {
+	next := func() ([]byte, bool) {
+		if len(data) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-4) {
+			return nil, false
+		}
+		value := data[4 : 4+n]
+		data = data[4+n:]
+		return value, true
+	}
+	if _, ok := next(); !ok { // vendor
+		return errorf("truncated Vorbis comment")
+	}
+	if len(data) < 4 {
+		return errorf("truncated Vorbis comment")
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	for i := uint32(0); i < count; i++ {
+		field, ok := next()
+		if !ok {
+			return errorf("truncated Vorbis comment")
+		}
+		key, value, ok := strings.Cut(string(field), "=")
+		if ok {
+			setComment(t, strings.ToUpper(key), value)
+		}
+	}
+	return nil
+}
+
+func setComment(t *Tags, key, value string) {
+	add := func(dst *string) {
+		if *dst == "" {
+			*dst = value
+		} else {
+			*dst += "/" + value // repeated fields are several values
+		}
+	}
+	switch key {
+	case "TITLE":
+		add(&t.Title)
+	case "ARTIST":
+		add(&t.Artist)
+	case "ALBUMARTIST", "ALBUM ARTIST":
+		add(&t.AlbumArtist)
+	case "ALBUM":
+		add(&t.Album)
+	case "GENRE":
+		add(&t.Genre)
+	case "DATE", "YEAR":
+		if t.Date == "" {
+			t.Date = value
+		}
+	case "TRACKNUMBER":
+		t.Track = value
+	case "LYRICS", "UNSYNCEDLYRICS":
+		if t.Lyrics == "" {
+			t.Lyrics = value
+		}
+	case "METADATA_BLOCK_PICTURE":
+		if block, err := base64.StdEncoding.DecodeString(value); err == nil {
+			if p, ok := flacPicture(block); ok {
+				t.Pictures = append(t.Pictures, p)
+			}
+		}
+	}
+}
+
+// flacPicture reads a PICTURE block, also used base64 encoded in Ogg comments
+func flacPicture(b []byte) (Picture, bool) {
+	var p Picture
+	read := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := binary.BigEndian.Uint32(b)
+		if uint64(n) > uint64(len(b)-4) {
+			return nil, false
+		}
+		value := b[4 : 4+n]
+		b = b[4+n:]
+		return value, true
+	}
+	if len(b) < 4 {
+		return p, false
+	}
+	p.Type = int(binary.BigEndian.Uint32(b))
+	b = b[4:]
+	mimeType, ok := read()
+	if !ok {
+		return p, false
+	}
+	desc, ok := read()
+	if !ok || len(b) < 16 {
+		return p, false
+	}
+	b = b[16:] // width, height, depth, colors
+	data, ok := read()
+	if !ok {
+		return p, false
+	}
+	p.MIMEType, p.Description, p.Data = string(mimeType), string(desc), data
+	return p, true
+}
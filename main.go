@@ -3,10 +3,18 @@ package main
 import (
 	"log"
 	_ "music_library_api/docs"
+	"music_library_api/internal/blob"
+	"music_library_api/internal/charts"
 	"music_library_api/internal/database"
 	"music_library_api/internal/endpoints"
+	"music_library_api/internal/enrichment"
+	"music_library_api/internal/idempotency"
+	"music_library_api/internal/ingest"
+	"music_library_api/internal/linkcheck"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -16,6 +24,7 @@ import (
 // @title Music API
 // @version 1.0
 // @description Test task : This is a simple music API to manage songs
+// @description POST, PUT, PATCH and DELETE accept an Idempotency-Key header: a retry with the same key and body gets the stored first response (Idempotent-Replayed: true), the same key with another body is rejected with 422
 // @termsOfService http://swagger.io/terms/
 
 // @contact.name API Support
@@ -39,16 +48,90 @@ func main() {
 	}
 
 	database.ConnectToPostgres()
+	enrichment.Setup(database.GetDB())
+	blob.Setup()
+
+	workers, _ := strconv.Atoi(os.Getenv("INGEST_WORKERS"))          // default 4
+	maxAttempts, _ := strconv.Atoi(os.Getenv("INGEST_MAX_ATTEMPTS")) // default 5
+	precedence, err := ingest.PolicyFromEnv()
+	if err != nil {
+		log.Fatal("Invalid ENRICHMENT_PRECEDENCE: ", err)
+	}
+	ingest.StartWorkers(database.GetDB(), workers, maxAttempts, precedence)
+
+	refreshInterval, _ := time.ParseDuration(os.Getenv("REENRICH_INTERVAL")) // default 1h
+	refreshAge, _ := time.ParseDuration(os.Getenv("REENRICH_MAX_AGE"))       // empty - disabled
+	ingest.StartRefresher(database.GetDB(), refreshInterval, refreshAge)
+
+	chartsInterval, _ := time.ParseDuration(os.Getenv("CHARTS_INTERVAL")) // default 15m
+	charts.StartWorker(database.GetDB(), chartsInterval)
+
+	linkCheck, err := linkcheck.ConfigFromEnv()
+	if err != nil {
+		log.Fatal("Invalid link checker settings: ", err)
+	}
+	linkcheck.StartWorker(database.GetDB(), linkCheck)
+
 	http.HandleFunc("/api/getSongs", endpoints.GetSongs)
 	http.HandleFunc("/api/AddSong", endpoints.AddSongs)
 	http.HandleFunc("/api/deleteSong", endpoints.DeleteSong)
 	http.HandleFunc("/api/EditSong", endpoints.EditSong)
 	http.HandleFunc("/api/GetSongText", endpoints.GetSongText)
+	http.HandleFunc("/api/RecordPlay", endpoints.RecordPlay)
+	http.HandleFunc("/api/AddFavorite", endpoints.AddFavorite)
+	http.HandleFunc("/api/DeleteFavorite", endpoints.DeleteFavorite)
+	http.HandleFunc("/api/charts", endpoints.GetCharts)
+	http.HandleFunc("/api/AddReview", endpoints.AddReview)
+	http.HandleFunc("/api/EditReview", endpoints.EditReview)
+	http.HandleFunc("/api/DeleteReview", endpoints.DeleteReview)
+	http.HandleFunc("/api/GetReviews", endpoints.GetReviews)
+	http.HandleFunc("/api/ModerateReview", endpoints.ModerateReview)
+	http.HandleFunc("/api/AddAnnotation", endpoints.AddAnnotation)
+	http.HandleFunc("/api/VoteAnnotation", endpoints.VoteAnnotation)
+	http.HandleFunc("/api/DeleteAnnotation", endpoints.DeleteAnnotation)
+	http.HandleFunc("/api/UploadSyncedLyrics", endpoints.UploadSyncedLyrics)
+	http.HandleFunc("/api/GetSyncedLyrics", endpoints.GetSyncedLyrics)
+	http.HandleFunc("/api/GetLyricsAtTime", endpoints.GetLyricsAtTime)
+	http.HandleFunc("/api/UploadTranslation", endpoints.UploadTranslation)
+	http.HandleFunc("/api/GetTranslations", endpoints.GetTranslations)
+	http.HandleFunc("/api/DeleteTranslation", endpoints.DeleteTranslation)
+	http.HandleFunc("/api/EnrichmentMetrics", endpoints.EnrichmentMetrics)
+	http.HandleFunc("/api/GetJob", endpoints.GetJob)
+	http.HandleFunc("/api/GetSongProvenance", endpoints.GetSongProvenance)
+	http.HandleFunc("/api/ReEnrichSongs", endpoints.ReEnrichSongs)
+	http.HandleFunc("/api/GetEnrichmentCache", endpoints.GetEnrichmentCache)
+	http.HandleFunc("/api/DeleteEnrichmentCache", endpoints.DeleteEnrichmentCache)
+	http.HandleFunc("/api/GetDuplicates", endpoints.GetDuplicates)
+	http.HandleFunc("/api/MergeSongs", endpoints.MergeSongs)
+	http.HandleFunc("/api/ImportSongs", endpoints.ImportSongs)
+	http.HandleFunc("/api/ExportSongs", endpoints.ExportSongs)
+	http.HandleFunc("/api/GetPlaylist", endpoints.GetPlaylist)
+	http.HandleFunc("/api/ImportPlaylist", endpoints.ImportPlaylist)
+	http.HandleFunc("/api/ExportPlaylist", endpoints.ExportPlaylist)
+	http.HandleFunc("/api/ImportLibrary", endpoints.ImportLibrary)
+	http.HandleFunc("/api/AddSongFromFile", endpoints.AddSongFromFile)
+	http.HandleFunc("/api/UploadAudio", endpoints.UploadAudio)
+	http.HandleFunc("/api/StreamAudio", endpoints.StreamAudio)
+	http.HandleFunc("/api/DeleteAudio", endpoints.DeleteAudio)
+	http.HandleFunc("/api/UploadCover", endpoints.UploadCover)
+	http.HandleFunc("/api/DeleteCover", endpoints.DeleteCover)
+	http.HandleFunc("/api/GetImage", endpoints.GetImage)
+	http.HandleFunc("/api/GetSongLinks", endpoints.GetSongLinks)
+	http.HandleFunc("/api/AddSongLink", endpoints.AddSongLink)
+	http.HandleFunc("/api/DeleteSongLink", endpoints.DeleteSongLink)
+	http.HandleFunc("/api/GetBrokenLinks", endpoints.GetBrokenLinks)
+	http.HandleFunc("/api/AddSongRelation", endpoints.AddSongRelation)
+	http.HandleFunc("/api/DeleteSongRelation", endpoints.DeleteSongRelation)
+	http.HandleFunc("/api/GetSongVersions", endpoints.GetSongVersions)
 
 	http.Handle("/swagger/", httpSwagger.WrapHandler) //http://localhost:8080/swagger/index.html
 
+	// repeated POST, PUT, PATCH and DELETE with the same Idempotency-Key get the first response
+	idempotencyTTL, _ := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")) // default 24h
+	handler := idempotency.Middleware(database.GetDB(), idempotencyTTL)(http.DefaultServeMux)
+
 	log.Printf("Server started on :%s", port)
 	// Start
-	http.ListenAndServe(":"+port, nil)
+	http.ListenAndServe(":"+port, handler)
 
 }